@@ -0,0 +1,253 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// Chore is one task in a chores file. Effort says how much of a burden it is
+// and defaults to 1.
+type Chore struct {
+	Name   string `toml:"name"`
+	Effort int    `toml:"effort,omitzero"`
+}
+
+// ChoreList is the layout of a chores file
+type ChoreList struct {
+	Chores []Chore `toml:"chores"`
+}
+
+// Assignment is one round of assign for a chores file
+type Assignment struct {
+	AssignedAt time.Time         `json:"assigned_at"`
+	Chores     map[string]string `json:"chores"`
+	Load       map[string]int    `json:"load"`
+}
+
+var assignCmd = &cobra.Command{
+	Use:   "assign [chores.toml]",
+	Short: "Randomly assign chores, weighted against whoever drew the most effort lately",
+	Long: `Randomly assign the chores in a TOML file to people:
+
+  [[chores]]
+  name = "dishes"
+  effort = 3
+
+Each chore goes to a random person, but the more effort someone has been
+given over the last --window rounds (and so far this round), the less
+likely they are to draw the next one. Rounds are remembered per file name.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		path := args[0]
+		people, _ := cmd.Flags().GetStringSlice("people")
+		window, _ := cmd.Flags().GetInt("window")
+
+		if len(people) == 0 {
+			log.Fatal("Specify who to assign to with --people")
+		}
+		if window < 0 {
+			log.Fatal("Window must be non-negative")
+		}
+
+		var list ChoreList
+		if _, err := toml.DecodeFile(path, &list); err != nil {
+			log.Fatal("Failed to load chores:", err)
+		}
+		if len(list.Chores) == 0 {
+			log.Fatal("No chores in ", path)
+		}
+		seen := map[string]bool{}
+		for i, chore := range list.Chores {
+			if chore.Name == "" {
+				log.Fatalf("Chore %d has no name", i+1)
+			}
+			if seen[chore.Name] {
+				log.Fatalf("Chore '%s' is listed twice", chore.Name)
+			}
+			seen[chore.Name] = true
+			if chore.Effort < 0 {
+				log.Fatalf("Effort for '%s' must be non-negative", chore.Name)
+			}
+			if chore.Effort == 0 {
+				list.Chores[i].Effort = 1
+			}
+		}
+
+		key := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		history, err := loadAssignments(key)
+		if err != nil {
+			log.Fatal("Failed to load assignment history:", err)
+		}
+
+		recent := map[string]int{}
+		for _, round := range history[max(len(history)-window, 0):] {
+			for person, load := range round.Load {
+				recent[person] += load
+			}
+		}
+
+		round := assignChores(list.Chores, people, recent)
+		if err := saveAssignment(key, round); err != nil {
+			log.Fatal("Failed to save assignment:", err)
+		}
+
+		if quiet {
+			for _, chore := range list.Chores {
+				fmt.Printf("%s\t%s\n", chore.Name, round.Chores[chore.Name])
+			}
+			return
+		}
+
+		if a11y {
+			for _, chore := range list.Chores {
+				fmt.Printf("%s goes to %s.\n", chore.Name, round.Chores[chore.Name])
+			}
+		} else {
+			fmt.Printf("\n🎲 Assigning %d chores to %d people...\n", len(list.Chores), len(people))
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "CHORE\tEFFORT\tPERSON")
+			for _, chore := range list.Chores {
+				fmt.Fprintf(w, "%s\t%d\t%s\n", chore.Name, chore.Effort, round.Chores[chore.Name])
+			}
+			w.Flush()
+		}
+
+		printFairness(people, recent, round, min(len(history), window))
+	},
+}
+
+func init() {
+	assignCmd.Flags().StringSlice("people", nil, "People to assign chores to, e.g. alice,bob,cara")
+	assignCmd.Flags().Int("window", 5, "Number of past rounds that count against a person")
+}
+
+// assignChores hands out chores one at a time, hardest first, picking each
+// person with weight 1/(1+extra) where extra is how much more effort they
+// carry than the least loaded person
+func assignChores(chores []Chore, people []string, recent map[string]int) Assignment {
+	round := Assignment{
+		AssignedAt: time.Now().UTC(),
+		Chores:     map[string]string{},
+		Load:       map[string]int{},
+	}
+
+	order := append([]Chore(nil), chores...)
+	rng.Shuffle(len(order), func(i, j int) { order[i], order[j] = order[j], order[i] })
+	sort.SliceStable(order, func(i, j int) bool { return order[i].Effort > order[j].Effort })
+
+	for _, chore := range order {
+		lowest := -1
+		for _, person := range people {
+			if load := recent[person] + round.Load[person]; lowest < 0 || load < lowest {
+				lowest = load
+			}
+		}
+
+		weights := make([]float64, len(people))
+		total := 0.0
+		for i, person := range people {
+			extra := recent[person] + round.Load[person] - lowest
+			weights[i] = 1 / float64(1+extra)
+			total += weights[i]
+		}
+
+		pick := rng.Float64() * total
+		chosen := people[len(people)-1]
+		for i, person := range people {
+			if pick < weights[i] {
+				chosen = person
+				break
+			}
+			pick -= weights[i]
+		}
+
+		round.Chores[chore.Name] = chosen
+		round.Load[chosen] += chore.Effort
+	}
+	return round
+}
+
+// printFairness shows each person's effort this round against what they
+// carried over the recent rounds
+func printFairness(people []string, recent map[string]int, round Assignment, rounds int) {
+	total := 0
+	for _, person := range people {
+		total += recent[person] + round.Load[person]
+	}
+
+	fmt.Println()
+	if a11y {
+		fmt.Printf("Fairness over the last %d rounds plus this one:\n", rounds)
+		for _, person := range people {
+			load := recent[person] + round.Load[person]
+			fmt.Printf("%s: %d effort this round, %d in total, %s of all effort.\n",
+				person, round.Load[person], load, formatPercent(float64(load)/float64(total)))
+		}
+		return
+	}
+
+	fmt.Printf("Fairness (last %d rounds + this one):\n", rounds)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "PERSON\tTHIS ROUND\tTOTAL\tSHARE")
+	for _, person := range people {
+		load := recent[person] + round.Load[person]
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\n", person, round.Load[person], load, formatPercent(float64(load)/float64(total)))
+	}
+	w.Flush()
+}
+
+// loadAssignments returns the past rounds for a chores file, oldest first
+func loadAssignments(key string) ([]Assignment, error) {
+	var rounds []Assignment
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("assignments"))
+		if b == nil {
+			return nil
+		}
+		data := b.Get([]byte(key))
+		if data == nil {
+			return nil
+		}
+		return json.Unmarshal(data, &rounds)
+	})
+	return rounds, err
+}
+
+// saveAssignment appends a round to a chores file's history, keeping only
+// as many rounds as the history limit
+func saveAssignment(key string, round Assignment) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("assignments"))
+		if err != nil {
+			return err
+		}
+
+		var rounds []Assignment
+		if data := b.Get([]byte(key)); data != nil {
+			if err := json.Unmarshal(data, &rounds); err != nil {
+				return err
+			}
+		}
+		rounds = append(rounds, round)
+		if len(rounds) > maxHistory {
+			rounds = rounds[len(rounds)-maxHistory:]
+		}
+
+		data, err := json.Marshal(rounds)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(key), data)
+	})
+}
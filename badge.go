@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+var badgeCmd = &cobra.Command{
+	Use:   "badge [name]",
+	Short: "Render an SVG badge of a configuration's current pity",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		label, _ := cmd.Flags().GetString("label")
+		output, _ := cmd.Flags().GetString("output")
+
+		config, err := loadConfig(name)
+		if err != nil {
+			log.Fatal("Failed to load config:", err)
+		}
+
+		state, err := viewState(name)
+		if err != nil {
+			log.Fatal("Failed to load state:", err)
+		}
+
+		if label == "" {
+			label = name
+		}
+		level := pityLevel(config, state, time.Now())
+		chance, err := successChance(config, level)
+		if err != nil {
+			log.Fatal("Failed to compute odds:", err)
+		}
+		value := fmt.Sprintf("pity: %d/%d, %s", level, config.Pity, formatPercent(chance))
+		svg := renderBadge(label, value)
+
+		if output == "" {
+			fmt.Print(svg)
+			return
+		}
+		if err := os.WriteFile(output, []byte(svg), 0644); err != nil {
+			log.Fatal("Failed to write badge:", err)
+		}
+		fmt.Printf("Badge saved to: %s\n", output)
+	},
+}
+
+func init() {
+	badgeCmd.Flags().String("label", "", "Left-hand badge text (defaults to the config name)")
+	badgeCmd.Flags().StringP("output", "o", "", "Write the SVG to this file instead of stdout")
+	badgeCmd.ValidArgsFunction = completeArgs(configNames)
+}
+
+// renderBadge draws a flat two-part badge. Widths are estimated from the
+// text length since the SVG is rendered without font metrics.
+func renderBadge(label, value string) string {
+	const charWidth, padding = 7, 10
+	lw := len(label)*charWidth + 2*padding
+	vw := len(value)*charWidth + 2*padding
+	label, value = html.EscapeString(label), html.EscapeString(value)
+
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[3]s: %[4]s">
+  <title>%[3]s: %[4]s</title>
+  <clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>
+  <g clip-path="url(#r)">
+    <rect width="%[2]d" height="20" fill="#555"/>
+    <rect x="%[2]d" width="%[5]d" height="20" fill="#e05d44"/>
+  </g>
+  <g fill="#fff" text-anchor="middle" font-family="Verdana,DejaVu Sans,sans-serif" font-size="11">
+    <text x="%[6]d" y="14">%[3]s</text>
+    <text x="%[7]d" y="14">%[4]s</text>
+  </g>
+</svg>
+`, lw+vw, lw, label, value, vw, lw/2, lw+vw/2)
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// BucketAssignment is a recorded bucket for a key, kept for audit
+type BucketAssignment struct {
+	Experiment string    `json:"experiment"`
+	Key        string    `json:"key"`
+	Bucket     string    `json:"bucket"`
+	Buckets    string    `json:"buckets"`
+	AssignedAt time.Time `json:"assigned_at"`
+}
+
+// BucketReport is the JSON printed for a bucket under --ci
+type BucketReport struct {
+	Experiment string `json:"experiment,omitempty"`
+	Key        string `json:"key"`
+	Bucket     string `json:"bucket"`
+}
+
+// weightedBucket is one name:weight entry of --buckets
+type weightedBucket struct {
+	Name   string
+	Weight float64
+}
+
+var bucketCmd = &cobra.Command{
+	Use:   "bucket",
+	Short: "Hash a key into one of several weighted buckets, e.g. for A/B tests",
+	Long: `Assign a key to a weighted bucket:
+
+  roll bucket --key user123 --buckets control:50,variant:50
+
+The same key always lands in the same bucket for the same --experiment and
+buckets, and keys spread across buckets in proportion to their weights.
+--record stores the assignment so it can be audited with bucket audit.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		key, _ := cmd.Flags().GetString("key")
+		spec, _ := cmd.Flags().GetString("buckets")
+		experiment, _ := cmd.Flags().GetString("experiment")
+		record, _ := cmd.Flags().GetBool("record")
+
+		if key == "" {
+			log.Fatal("Specify the key to assign with --key")
+		}
+		buckets, err := parseBuckets(spec)
+		if err != nil {
+			log.Fatal("Invalid --buckets: ", err)
+		}
+
+		fraction := hashFraction(experiment, key)
+		bucket := pickBucket(buckets, fraction)
+
+		var previous *BucketAssignment
+		if record {
+			assignment := BucketAssignment{
+				Experiment: experiment,
+				Key:        key,
+				Bucket:     bucket,
+				Buckets:    spec,
+				AssignedAt: time.Now().UTC(),
+			}
+			previous, err = recordBucket(assignment)
+			if err != nil {
+				log.Fatal("Failed to record assignment:", err)
+			}
+		}
+
+		if verbose {
+			fmt.Printf("Hash of '%s': %s\n", key, formatFloat(fraction, 6))
+		}
+
+		switch {
+		case ci:
+			data, err := json.Marshal(BucketReport{Experiment: experiment, Key: key, Bucket: bucket})
+			if err != nil {
+				log.Fatal("Failed to print result:", err)
+			}
+			fmt.Println(string(data))
+			return
+		case quiet:
+			fmt.Println(bucket)
+			return
+		case a11y:
+			fmt.Printf("%s is in bucket %s.\n", key, bucket)
+		default:
+			fmt.Printf("🎲 '%s' → %s\n", key, bucket)
+		}
+
+		if previous != nil && previous.Bucket != bucket {
+			fmt.Printf("Changed from %s, recorded %s with buckets %s\n",
+				previous.Bucket, formatTime(previous.AssignedAt), previous.Buckets)
+		}
+	},
+}
+
+var bucketAuditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "List recorded bucket assignments",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		experiment, _ := cmd.Flags().GetString("experiment")
+		all := !cmd.Flags().Changed("experiment")
+
+		var assignments []BucketAssignment
+		err := db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte("bucket_assignments"))
+			if b == nil {
+				return nil
+			}
+			return b.ForEach(func(k, v []byte) error {
+				var a BucketAssignment
+				if err := json.Unmarshal(v, &a); err != nil {
+					return err
+				}
+				if all || a.Experiment == experiment {
+					assignments = append(assignments, a)
+				}
+				return nil
+			})
+		})
+		if err != nil {
+			log.Fatal("Failed to load assignments:", err)
+		}
+		if len(assignments) == 0 {
+			fmt.Println("No recorded assignments")
+			return
+		}
+
+		sort.Slice(assignments, func(i, j int) bool { return assignments[i].AssignedAt.Before(assignments[j].AssignedAt) })
+
+		counts := map[string]int{}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if !quiet && !a11y {
+			fmt.Fprintln(w, "EXPERIMENT\tKEY\tBUCKET\tRECORDED")
+		}
+		for _, a := range assignments {
+			counts[a.Bucket]++
+			switch {
+			case quiet:
+				fmt.Fprintf(w, "%s\t%s\t%s\n", a.Experiment, a.Key, a.Bucket)
+			case a11y:
+				fmt.Printf("%s is in bucket %s of experiment '%s', recorded %s.\n",
+					a.Key, a.Bucket, a.Experiment, formatTime(a.AssignedAt))
+			default:
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", a.Experiment, a.Key, a.Bucket, formatTime(a.AssignedAt))
+			}
+		}
+		w.Flush()
+
+		if quiet {
+			return
+		}
+		names := make([]string, 0, len(counts))
+		for name := range counts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		fmt.Println()
+		for _, name := range names {
+			fmt.Printf("%s: %d (%s)\n", name, counts[name], formatPercent(float64(counts[name])/float64(len(assignments))))
+		}
+	},
+}
+
+func init() {
+	bucketCmd.AddCommand(bucketAuditCmd)
+	bucketCmd.Flags().String("key", "", "Key to assign, e.g. a user id")
+	bucketCmd.Flags().String("buckets", "control:50,variant:50", "Weighted buckets as name:weight,name:weight")
+	bucketCmd.PersistentFlags().String("experiment", "", "Experiment name, mixed into the hash so experiments split keys differently")
+	bucketCmd.Flags().Bool("record", false, "Record the assignment for audit")
+}
+
+// parseBuckets reads name:weight pairs; a name alone has weight 1
+func parseBuckets(spec string) ([]weightedBucket, error) {
+	var buckets []weightedBucket
+	seen := map[string]bool{}
+	total := 0.0
+	for _, part := range strings.Split(spec, ",") {
+		name, weightText, hasWeight := strings.Cut(strings.TrimSpace(part), ":")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, fmt.Errorf("%q has no bucket name", part)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("'%s' is listed twice", name)
+		}
+		seen[name] = true
+
+		weight := 1.0
+		if hasWeight {
+			w, err := strconv.ParseFloat(strings.TrimSpace(weightText), 64)
+			if err != nil || w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+				return nil, fmt.Errorf("weight for '%s' must be a non-negative number", name)
+			}
+			weight = w
+		}
+		total += weight
+		buckets = append(buckets, weightedBucket{name, weight})
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("weights must add up to more than 0")
+	}
+	return buckets, nil
+}
+
+// pickBucket finds the bucket whose share of the total weight covers
+// fraction, a number in [0, 1)
+func pickBucket(buckets []weightedBucket, fraction float64) string {
+	weights := make([]float64, len(buckets))
+	for i, b := range buckets {
+		weights[i] = b.Weight
+	}
+	shares := normalise(weights)
+
+	last := ""
+	for i, share := range shares {
+		if share == 0 {
+			continue
+		}
+		if fraction < share {
+			return buckets[i].Name
+		}
+		fraction -= share
+		last = buckets[i].Name
+	}
+	return last
+}
+
+// recordBucket stores an assignment, returning the one it replaced if any
+func recordBucket(assignment BucketAssignment) (*BucketAssignment, error) {
+	var previous *BucketAssignment
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("bucket_assignments"))
+		if err != nil {
+			return err
+		}
+		key := []byte(assignment.Experiment + "/" + assignment.Key)
+		if data := b.Get(key); data != nil {
+			previous = &BucketAssignment{}
+			if err := json.Unmarshal(data, previous); err != nil {
+				return err
+			}
+		}
+
+		data, err := json.Marshal(assignment)
+		if err != nil {
+			return err
+		}
+		return b.Put(key, data)
+	})
+	return previous, err
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// exitRollFailed is the exit code of a failed roll under --ci, kept apart
+// from 1, which means the command itself went wrong
+const exitRollFailed = 2
+
+// ci turns on machine mode: plain output without emoji everywhere, and JSON
+// with strict exit codes from roll
+var ci bool
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&ci, "ci", false, "Machine mode for pipelines: JSON from roll, no emoji, exit code 2 when a roll fails")
+}
+
+// RollReport is the JSON printed for a roll under --ci
+type RollReport struct {
+	Name            string        `json:"name"`
+	Success         bool          `json:"success"`
+	Roll            int           `json:"roll"`
+	EffectiveChance int           `json:"effective_chance"`
+	Pity            int           `json:"pity"`
+	Margin          int           `json:"margin"`
+	Degree          string        `json:"degree,omitempty"`
+	Checks          []CheckReport `json:"checks,omitempty"`
+	Seed            int64         `json:"seed"`
+}
+
+// CheckReport is one check of a compound roll in a RollReport
+type CheckReport struct {
+	Name   string `json:"name"`
+	Chance int    `json:"chance"`
+	Roll   int    `json:"roll"`
+	Passed bool   `json:"passed"`
+}
+
+// printRollJSON prints a roll as a single line of JSON
+func printRollJSON(name string, result RollResult) error {
+	report := RollReport{
+		Name:            name,
+		Success:         result.Success,
+		Roll:            result.Roll,
+		EffectiveChance: result.EffectiveChance,
+		Pity:            result.Pity,
+		Margin:          result.Margin,
+		Degree:          result.Degree,
+		Seed:            rngSeed,
+	}
+	for _, check := range result.Checks {
+		report.Checks = append(report.Checks, CheckReport(check))
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(data))
+	return nil
+}
+
+// rollWithStateFile is rollAndRecord for a state kept in a JSON file of
+// config name to state, which CI caches can carry between runs. Group locks
+// and spark counters live in the database, so configs using them are refused.
+func rollWithStateFile(name string, config *Config, path string) (RollResult, error) {
+	if config.Group != "" || config.SparkGroup != "" {
+		return RollResult{}, fmt.Errorf("'%s' uses a group or spark group, which --state-file cannot track", name)
+	}
+
+	states := map[string]State{}
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if strict {
+			return RollResult{}, fmt.Errorf("state file %s not found", path)
+		}
+	case err != nil:
+		return RollResult{}, err
+	default:
+		if err := json.Unmarshal(data, &states); err != nil {
+			return RollResult{}, fmt.Errorf("invalid state file: %w", err)
+		}
+	}
+
+	state, ok := states[name]
+	if !ok && strict {
+		return RollResult{}, fmt.Errorf("state not found for %s", name)
+	}
+
+	result := rollConfig(config, &state)
+	printRoll(name, config, result)
+	states[name] = state
+
+	// Write a temporary file and rename it so a cancelled job cannot leave
+	// a half-written state behind
+	data, err = json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return result, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".roll-state-*")
+	if err != nil {
+		return result, err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return result, err
+	}
+	if err := tmp.Close(); err != nil {
+		return result, err
+	}
+	return result, os.Rename(tmp.Name(), path)
+}
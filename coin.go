@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var coinCmd = &cobra.Command{
+	Use:   "coin [count]",
+	Short: "Flip one or more coins and count heads and tails",
+	Long: `Flip a coin, or count coins at once, and total the heads and tails:
+
+  roll coin
+  roll coin 10 --bias 0.7
+
+--bias is the chance of heads for every coin, 0.5 for a fair coin. With
+--quiet a single coin prints heads or tails and several print the number
+of heads.`,
+	Args: cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		bias, _ := cmd.Flags().GetFloat64("bias")
+
+		count := 1
+		if len(args) == 1 {
+			n, err := strconv.Atoi(args[0])
+			if err != nil || n < 1 || n > maxDice {
+				log.Fatalf("Count must be a whole number between 1 and %d", maxDice)
+			}
+			count = n
+		}
+		if bias < 0 || bias > 1 {
+			log.Fatal("Bias must be between 0 and 1")
+		}
+
+		if verbose {
+			printProvenance()
+		}
+		flips := make([]bool, count)
+		heads := 0
+		for i := range flips {
+			draw := rng.Float64()
+			flips[i] = draw < bias
+			if flips[i] {
+				heads++
+			}
+			if verbose {
+				fmt.Printf("Raw draw: %s of [0, 1), %s (heads below %s)\n",
+					formatFloat(draw, 4), coinSide(flips[i]), formatFloat(bias, 4))
+			}
+		}
+		tails := count - heads
+
+		if quiet {
+			if count == 1 {
+				fmt.Println(coinSide(flips[0]))
+			} else {
+				fmt.Println(heads)
+			}
+			return
+		}
+
+		sides := make([]string, count)
+		for i, flip := range flips {
+			sides[i] = coinSide(flip)
+		}
+
+		if a11y {
+			if count == 1 {
+				fmt.Printf("Flipping a coin. Result: %s.\n", sides[0])
+				return
+			}
+			fmt.Printf("Flipping %d coins. Result: %s and %s.\n", count,
+				plural(heads, "head", "heads"), plural(tails, "tail", "tails"))
+			fmt.Printf("The coins showed %s.\n", strings.Join(sides, ", "))
+			return
+		}
+
+		if count == 1 {
+			fmt.Printf("\n🪙 Flipping a coin...\n")
+			fmt.Printf("Result: %s\n", sides[0])
+			return
+		}
+		fmt.Printf("\n🪙 Flipping %d coins...\n", count)
+		letters := make([]string, count)
+		for i, side := range sides {
+			letters[i] = strings.ToUpper(side[:1])
+		}
+		fmt.Printf("Coins: %s\n", strings.Join(letters, " "))
+		fmt.Printf("Heads: %d (%s)\n", heads, formatPercent(float64(heads)/float64(count)))
+		fmt.Printf("Tails: %d (%s)\n", tails, formatPercent(float64(tails)/float64(count)))
+		if bias != 0.5 {
+			fmt.Printf("Bias: %s heads\n", formatPercent(bias))
+		}
+	},
+}
+
+func init() {
+	coinCmd.Flags().Float64("bias", 0.5, "Chance of heads for each coin, from 0 to 1")
+}
+
+func coinSide(heads bool) string {
+	if heads {
+		return "heads"
+	}
+	return "tails"
+}
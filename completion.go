@@ -0,0 +1,86 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// commonDice are suggested for dice expressions alongside recent rolls
+var commonDice = []string{"d20", "2d20kh1", "2d20kl1", "d6", "2d6", "3d6", "4d6kh3", "d8", "d10", "d12", "d%", "4dF", "d66"}
+
+// completionProvider lists every value an argument or flag could take.
+// Suggestions are filtered to what has been typed so far by complete.
+type completionProvider func() []string
+
+// completeArgs suggests positional arguments in order: the first from the
+// first provider, and so on. Arguments past the last provider get nothing.
+func completeArgs(providers ...completionProvider) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		if len(args) >= len(providers) {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		return complete(providers[len(args)], toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeEach suggests every positional argument from the same provider,
+// for commands that take a list of names
+func completeEach(provider completionProvider) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return complete(provider, toComplete), cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeFlag suggests the value of a flag. List flags such as --tables
+// action,theme are completed one comma-separated item at a time.
+func completeFlag(provider completionProvider) func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		done, last := "", toComplete
+		if i := strings.LastIndex(toComplete, ","); i >= 0 {
+			done, last = toComplete[:i+1], toComplete[i+1:]
+		}
+		suggestions := complete(provider, last)
+		for i := range suggestions {
+			suggestions[i] = done + suggestions[i]
+		}
+		return suggestions, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// complete returns the provider's values starting with prefix, sorted and
+// without repeats
+func complete(provider completionProvider, prefix string) []string {
+	seen := map[string]bool{}
+	var suggestions []string
+	for _, value := range provider() {
+		if strings.HasPrefix(value, prefix) && !seen[value] {
+			seen[value] = true
+			suggestions = append(suggestions, value)
+		}
+	}
+	sort.Strings(suggestions)
+	return suggestions
+}
+
+// groupNames returns every mutual exclusion group used by a config
+func groupNames() []string {
+	var names []string
+	for name := range groupMembers() {
+		names = append(names, name)
+	}
+	return names
+}
+
+// diceSuggestions returns macro names, the expressions in dice history and
+// common ones
+func diceSuggestions() []string {
+	suggestions := macroNames()
+	if records, err := loadDiceHistory(); err == nil {
+		for _, record := range records {
+			suggestions = append(suggestions, record.Expr)
+		}
+	}
+	return append(suggestions, commonDice...)
+}
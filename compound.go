@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"math"
+)
+
+// Check is one sub-roll of a compound config
+type Check struct {
+	Name   string `toml:"name"`
+	Chance int    `toml:"chance"`
+}
+
+// CheckResult is the outcome of one sub-roll
+type CheckResult struct {
+	Name   string
+	Chance int
+	Roll   int
+	Passed bool
+}
+
+// requiredChecks returns how many checks must pass, defaulting to all of them
+func requiredChecks(config *Config) int {
+	if config.Require == 0 {
+		return len(config.Checks)
+	}
+	return config.Require
+}
+
+// rollChecks rolls every check with the grace bonus added to its chance
+func rollChecks(config *Config, bonus int) ([]CheckResult, int) {
+	results := make([]CheckResult, len(config.Checks))
+	passed := 0
+	for i, check := range config.Checks {
+		chance := min(check.Chance+bonus, 100)
+		roll := rng.Intn(100) + 1
+		results[i] = CheckResult{Name: check.Name, Chance: chance, Roll: roll, Passed: roll <= chance}
+		if results[i].Passed {
+			passed++
+		}
+	}
+	return results, passed
+}
+
+// passChance returns the probability that a roll at the given pity succeeds
+// with the given grace bonus. For compound configs this is the chance that at
+// least the required number of independent checks pass.
+func passChance(config *Config, pity, bonus int) (float64, error) {
+	capChance := func(c int) float64 {
+		return float64(max(min(c, 100), 0)) / 100
+	}
+	if len(config.Checks) == 0 && config.Success != "" {
+		// Count the rolls the expression accepts
+		chance := min(config.Chance+bonus, 100)
+		passing := 0
+		for roll := 1; roll <= 100; roll++ {
+			ok, err := rollSucceeds(config, roll, chance, pity)
+			if err != nil {
+				return 0, err
+			}
+			if ok {
+				passing++
+			}
+		}
+		return float64(passing) / 100, nil
+	}
+	if len(config.Checks) == 0 {
+		return capChance(config.Chance + bonus), nil
+	}
+
+	// dist[k] is the probability that exactly k checks pass so far
+	dist := []float64{1}
+	for _, check := range config.Checks {
+		p := capChance(check.Chance + bonus)
+		next := make([]float64, len(dist)+1)
+		for k, mass := range dist {
+			next[k] += mass * (1 - p)
+			next[k+1] += mass * p
+		}
+		dist = next
+	}
+
+	chance := 0.0
+	for k := requiredChecks(config); k < len(dist); k++ {
+		chance += dist[k]
+	}
+	return chance, nil
+}
+
+// validateChecks checks the sub-rolls of a compound config
+func validateChecks(config *Config) []error {
+	var errs []error
+	if len(config.Checks) == 0 {
+		if config.Require != 0 {
+			errs = append(errs, fmt.Errorf("Require needs at least one check"))
+		}
+		return errs
+	}
+
+	if config.Require < 0 || config.Require > len(config.Checks) {
+		errs = append(errs, fmt.Errorf("Require must be between 1 and the number of checks (%d)", len(config.Checks)))
+	}
+	for i, check := range config.Checks {
+		if check.Name == "" {
+			errs = append(errs, fmt.Errorf("Check %d needs a name", i+1))
+		}
+		if check.Chance < 0 || check.Chance > 100 {
+			errs = append(errs, fmt.Errorf("Check '%s' chance must be between 0 and 100", check.Name))
+		}
+	}
+	return errs
+}
+
+// printChecks prints the breakdown of a compound roll
+func printChecks(config *Config, checks []CheckResult) {
+	passed := 0
+	for _, check := range checks {
+		if check.Passed {
+			passed++
+		}
+	}
+
+	if a11y {
+		fmt.Printf("%d of %d checks passed, %d needed.\n", passed, len(checks), requiredChecks(config))
+		for _, check := range checks {
+			outcome := "failed"
+			if check.Passed {
+				outcome = "passed"
+			}
+			fmt.Printf("%s %s: rolled %d against %d percent.\n", check.Name, outcome, check.Roll, check.Chance)
+		}
+		return
+	}
+
+	fmt.Printf("Checks (%d of %d passed, need %d):\n", passed, len(checks), requiredChecks(config))
+	for _, check := range checks {
+		mark := "✗"
+		if check.Passed {
+			mark = "✓"
+		}
+		fmt.Printf("  %s %s: rolled %d vs %d%%\n", mark, check.Name, check.Roll, check.Chance)
+	}
+}
+
+// percent rounds a probability to a whole percentage
+func percent(p float64) int {
+	return int(math.Round(p * 100))
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// editErrorPrefix marks the validation errors written at the top of a
+// config being edited, so they can be stripped before the file is saved
+const editErrorPrefix = "# ERROR: "
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Manage roll configuration files",
+}
+
+var configEditCmd = &cobra.Command{
+	Use:   "edit [name]",
+	Short: "Edit a roll configuration in $EDITOR",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		configPath := filepath.Join(configDir, name+".toml")
+
+		original, err := os.ReadFile(configPath)
+		if err != nil {
+			log.Fatal("Failed to read config file:", err)
+		}
+
+		// Edit a scratch copy so the real file is only replaced once valid
+		tmp, err := os.CreateTemp("", name+"-*.toml")
+		if err != nil {
+			log.Fatal("Failed to create temp file:", err)
+		}
+		tmpPath := tmp.Name()
+		tmp.Close()
+		defer os.Remove(tmpPath)
+
+		if err := os.WriteFile(tmpPath, original, 0644); err != nil {
+			log.Fatal("Failed to write temp file:", err)
+		}
+
+		var lastInvalid []byte
+		for {
+			if err := runEditor(tmpPath); err != nil {
+				log.Fatal("Editor failed:", err)
+			}
+
+			edited, err := os.ReadFile(tmpPath)
+			if err != nil {
+				log.Fatal("Failed to read edited config:", err)
+			}
+			edited = stripEditErrors(edited)
+
+			if bytes.Equal(edited, original) {
+				fmt.Println("No changes made")
+				return
+			}
+
+			errs := checkConfigData(name, edited)
+			if len(errs) == 0 {
+				if err := os.WriteFile(configPath, edited, 0644); err != nil {
+					log.Fatal("Failed to save config:", err)
+				}
+				if err := snapshotConfig(name); err != nil {
+					log.Fatal("Failed to record config version:", err)
+				}
+				fmt.Printf("Saved configuration '%s'\n", name)
+				return
+			}
+
+			// Leaving an invalid file untouched gives up on the edit
+			if bytes.Equal(edited, lastInvalid) {
+				for _, err := range errs {
+					fmt.Fprintf(os.Stderr, "  %v\n", err)
+				}
+				log.Fatal("Config is invalid, changes discarded")
+			}
+			lastInvalid = edited
+
+			var buf bytes.Buffer
+			for _, err := range errs {
+				fmt.Fprintf(&buf, "%s%s\n", editErrorPrefix, strings.ReplaceAll(err.Error(), "\n", " "))
+			}
+			buf.Write(edited)
+			if err := os.WriteFile(tmpPath, buf.Bytes(), 0644); err != nil {
+				log.Fatal("Failed to write temp file:", err)
+			}
+		}
+	},
+}
+
+var configDiffCmd = &cobra.Command{
+	Use:   "diff [name]",
+	Short: "Show how a roll configuration changed between versions",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		versions, err := loadConfigVersions(name)
+		if err != nil {
+			log.Fatal("Failed to load config versions:", err)
+		}
+		if len(versions) == 0 {
+			log.Fatalf("No versions recorded for '%s'", name)
+		}
+
+		state, _ := loadState(name)
+
+		fmt.Printf("Version 1 (%s): first recorded\n", formatTime(versions[0].SavedAt))
+		for i := 1; i < len(versions); i++ {
+			fmt.Printf("\nVersion %d (%s):\n", i+1, formatTime(versions[i].SavedAt))
+			for _, line := range diffLines(versions[i-1].Data, versions[i].Data) {
+				fmt.Printf("  %s\n", line)
+			}
+		}
+
+		if state != nil && !state.LastRolledAt.IsZero() {
+			fmt.Printf("\nLast roll: %s\n", formatTime(state.LastRolledAt))
+		}
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configEditCmd)
+	configCmd.AddCommand(configDiffCmd)
+	configEditCmd.ValidArgsFunction = completeArgs(configNames)
+	configDiffCmd.ValidArgsFunction = completeArgs(configNames)
+}
+
+// ConfigVersion is a snapshot of a config file as it was saved
+type ConfigVersion struct {
+	SavedAt time.Time `json:"saved_at"`
+	Data    string    `json:"data"`
+}
+
+// snapshotConfig stores the config file as a new version if it differs from
+// the most recent one
+func snapshotConfig(name string) error {
+	data, err := os.ReadFile(filepath.Join(configDir, name+".toml"))
+	if err != nil {
+		return err
+	}
+
+	return db.Update(func(tx *bolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists([]byte("versions"))
+		if err != nil {
+			return err
+		}
+		b, err := root.CreateBucketIfNotExists([]byte(name))
+		if err != nil {
+			return err
+		}
+
+		if _, last := b.Cursor().Last(); last != nil {
+			var version ConfigVersion
+			if err := json.Unmarshal(last, &version); err != nil {
+				return err
+			}
+			if version.Data == string(data) {
+				return nil
+			}
+		}
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+
+		value, err := json.Marshal(ConfigVersion{SavedAt: time.Now().UTC(), Data: string(data)})
+		if err != nil {
+			return err
+		}
+		return b.Put(key, value)
+	})
+}
+
+// loadConfigVersions returns all recorded versions of a config, oldest first
+func loadConfigVersions(name string) ([]ConfigVersion, error) {
+	var versions []ConfigVersion
+	err := db.View(func(tx *bolt.Tx) error {
+		root := tx.Bucket([]byte("versions"))
+		if root == nil {
+			return nil
+		}
+		b := root.Bucket([]byte(name))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(k, v []byte) error {
+			var version ConfigVersion
+			if err := json.Unmarshal(v, &version); err != nil {
+				return err
+			}
+			versions = append(versions, version)
+			return nil
+		})
+	})
+	return versions, err
+}
+
+// diffLines returns a line diff of two texts, with removed lines prefixed by
+// "-" and added lines by "+"
+func diffLines(a, b string) []string {
+	x := strings.Split(strings.TrimRight(a, "\n"), "\n")
+	y := strings.Split(strings.TrimRight(b, "\n"), "\n")
+
+	// Longest common subsequence table
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			i++
+			j++
+		case i < len(x) && (j == len(y) || lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+x[i])
+			i++
+		default:
+			out = append(out, "+ "+y[j])
+			j++
+		}
+	}
+	return out
+}
+
+// tomlKeys returns the TOML keys of a struct such as Config or Settings
+func tomlKeys(v any) []string {
+	var keys []string
+	t := reflect.TypeOf(v)
+	for i := 0; i < t.NumField(); i++ {
+		if tag, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ","); tag != "" {
+			keys = append(keys, tag)
+		}
+	}
+	return keys
+}
+
+// describeUnknownKeys lists undecoded keys along with the closest of the
+// valid keys when one is near enough to be a likely typo
+func describeUnknownKeys(undecoded []toml.Key, keys []string) string {
+	var parts []string
+	for _, key := range undecoded {
+		name := key[len(key)-1]
+		best, bestDist := "", 3
+		for _, valid := range keys {
+			if d := editDistance(name, valid); d < bestDist {
+				best, bestDist = valid, d
+			}
+		}
+
+		if best != "" {
+			parts = append(parts, fmt.Sprintf("%s (did you mean %s?)", key, best))
+		} else {
+			parts = append(parts, key.String())
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// editDistance returns the Levenshtein distance between a and b
+func editDistance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// runEditor opens path in $EDITOR (falling back to vi) attached to the terminal
+func runEditor(path string) error {
+	editor := strings.Fields(os.Getenv("EDITOR"))
+	if len(editor) == 0 {
+		editor = []string{"vi"}
+	}
+
+	c := exec.Command(editor[0], append(editor[1:], path)...)
+	c.Stdin = os.Stdin
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	return c.Run()
+}
+
+// stripEditErrors removes error lines added by a previous failed edit
+func stripEditErrors(data []byte) []byte {
+	var out []byte
+	for _, line := range bytes.SplitAfter(data, []byte("\n")) {
+		if bytes.HasPrefix(line, []byte(editErrorPrefix)) {
+			continue
+		}
+		out = append(out, line...)
+	}
+	return out
+}
+
+// checkConfigData decodes and validates the TOML for the named config
+func checkConfigData(name string, data []byte) []error {
+	var config Config
+	meta, err := toml.Decode(string(data), &config)
+	if err != nil {
+		return []error{err}
+	}
+
+	errs := validateConfig(&config)
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		errs = append(errs, fmt.Errorf("Unknown keys: %s", describeUnknownKeys(undecoded, tomlKeys(Config{}))))
+	}
+	if config.Name != name {
+		errs = append(errs, fmt.Errorf("Name must stay '%s'", name))
+	}
+	return errs
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+)
+
+// Option is one choice in an options file. Its score is the weighted sum of
+// its criteria scores, or Weight (default 1) when it has none. Weight is a
+// pointer so that an explicit 0 can rule an option out.
+type Option struct {
+	Name   string             `toml:"name"`
+	Weight *float64           `toml:"weight"`
+	Scores map[string]float64 `toml:"scores"`
+}
+
+// weight returns the option's weight, 1 when none is given
+func (o Option) weight() float64 {
+	if o.Weight == nil {
+		return 1
+	}
+	return *o.Weight
+}
+
+// OptionList is the layout of an options file. Criteria weights default to 1.
+type OptionList struct {
+	Criteria map[string]float64 `toml:"criteria"`
+	Options  []Option           `toml:"options"`
+}
+
+var decideCmd = &cobra.Command{
+	Use:   "decide [options.toml]",
+	Short: "Pick between weighted or scored options and explain why",
+	Long: `Pick one of the options in a TOML file:
+
+  [criteria]
+  cost = 2
+  fun = 1
+
+  [[options]]
+  name = "pizza"
+  scores = { cost = 3, fun = 5 }
+
+  [[options]]
+  name = "salad"
+  weight = 2
+
+An option's score is the sum of its criteria scores times the criteria
+weights, or its weight if it has no scores. With --mode sample (the
+default) each option is picked in proportion to its score; with --mode best
+the top score wins and ties are broken at random in proportion to weight.
+Weights default to 1, and weight = 0 keeps an option from being picked.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		mode, _ := cmd.Flags().GetString("mode")
+
+		var list OptionList
+		if _, err := toml.DecodeFile(args[0], &list); err != nil {
+			log.Fatal("Failed to load options:", err)
+		}
+		if len(list.Options) == 0 {
+			log.Fatal("No options in ", args[0])
+		}
+		if mode != "sample" && mode != "best" {
+			log.Fatal("Mode must be sample or best")
+		}
+
+		scores := make([]float64, len(list.Options))
+		for i, option := range list.Options {
+			if option.Name == "" {
+				log.Fatalf("Option %d has no name", i+1)
+			}
+			if w := option.weight(); w < 0 || math.IsNaN(w) {
+				log.Fatalf("Weight for '%s' must be non-negative", option.Name)
+			}
+			scores[i] = optionScore(option, list.Criteria)
+			if mode == "sample" && scores[i] < 0 {
+				log.Fatalf("Score for '%s' is negative, so it cannot be sampled; use --mode best", option.Name)
+			}
+		}
+
+		var chances []float64
+		var ties []int
+		if mode == "sample" {
+			chances = normalise(scores)
+			if chances == nil {
+				log.Fatal("Every option scores 0, so there is nothing to sample")
+			}
+		} else {
+			ties = topScores(scores)
+			weights := make([]float64, len(scores))
+			for _, i := range ties {
+				weights[i] = list.Options[i].weight()
+			}
+			chances = normalise(weights)
+			if chances == nil {
+				log.Fatal("Every option tied for the top score weighs 0, so there is nothing to pick")
+			}
+		}
+		picked := pickWeighted(chances)
+
+		if verbose {
+			printProvenance()
+		}
+		option := list.Options[picked]
+		if quiet {
+			fmt.Println(option.Name)
+			return
+		}
+
+		if a11y {
+			spoken := strings.NewReplacer("×", " times ", " + ", " plus ")
+			for i, o := range list.Options {
+				fmt.Printf("%s: score %s, chance %s. %s.\n", o.Name, formatFloat(scores[i], settings.Precision),
+					formatPercent(chances[i]), spoken.Replace(optionBreakdown(o, list.Criteria)))
+			}
+		} else {
+			fmt.Printf("\n🎲 Deciding between %d options (%s)...\n", len(list.Options), mode)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "OPTION\tSCORE\tCHANCE\tBREAKDOWN")
+			for i, o := range list.Options {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", o.Name, formatFloat(scores[i], settings.Precision),
+					formatPercent(chances[i]), optionBreakdown(o, list.Criteria))
+			}
+			w.Flush()
+			fmt.Println()
+			fmt.Print("✅ ")
+		}
+
+		switch {
+		case mode == "sample":
+			fmt.Printf("Picked: %s (score %s, a %s chance)\n", option.Name,
+				formatFloat(scores[picked], settings.Precision), formatPercent(chances[picked]))
+		case len(ties) > 1:
+			var names []string
+			for _, t := range ties {
+				if t != picked {
+					names = append(names, list.Options[t].Name)
+				}
+			}
+			fmt.Printf("Picked: %s (tied on score %s with %s; broken at random by weight)\n", option.Name,
+				formatFloat(scores[picked], settings.Precision), strings.Join(names, ", "))
+		default:
+			fmt.Printf("Picked: %s (highest score, %s)\n", option.Name, formatFloat(scores[picked], settings.Precision))
+		}
+	},
+}
+
+func init() {
+	decideCmd.Flags().String("mode", "sample", "sample picks in proportion to score; best picks the top score")
+}
+
+// optionScore sums an option's criteria scores times the criteria weights,
+// falling back to its weight when it has no scores
+func optionScore(option Option, criteria map[string]float64) float64 {
+	if len(option.Scores) == 0 {
+		return option.weight()
+	}
+	score := 0.0
+	for criterion, s := range option.Scores {
+		weight, ok := criteria[criterion]
+		if !ok {
+			weight = 1
+		}
+		score += s * weight
+	}
+	return score
+}
+
+// optionBreakdown explains an option's score, e.g. "cost 3×2 + fun 5×1"
+func optionBreakdown(option Option, criteria map[string]float64) string {
+	if len(option.Scores) == 0 {
+		return "weight " + formatFloat(option.weight(), settings.Precision)
+	}
+
+	names := make([]string, 0, len(option.Scores))
+	for criterion := range option.Scores {
+		names = append(names, criterion)
+	}
+	sort.Strings(names)
+
+	parts := make([]string, len(names))
+	for i, criterion := range names {
+		weight, ok := criteria[criterion]
+		if !ok {
+			weight = 1
+		}
+		parts[i] = fmt.Sprintf("%s %g×%g", criterion, option.Scores[criterion], weight)
+	}
+	return strings.Join(parts, " + ")
+}
+
+// topScores returns the indexes of every option sharing the highest score
+func topScores(scores []float64) []int {
+	var top []int
+	for i, s := range scores {
+		switch {
+		case len(top) == 0 || s > scores[top[0]]:
+			top = []int{i}
+		case s == scores[top[0]]:
+			top = append(top, i)
+		}
+	}
+	return top
+}
+
+// normalise scales weights to sum to 1, or returns nil if they sum to 0
+func normalise(weights []float64) []float64 {
+	total := 0.0
+	for _, w := range weights {
+		total += w
+	}
+	if total <= 0 {
+		return nil
+	}
+	chances := make([]float64, len(weights))
+	for i, w := range weights {
+		chances[i] = w / total
+	}
+	return chances
+}
+
+// pickWeighted draws an index with the given probabilities
+func pickWeighted(chances []float64) int {
+	pick := rng.Float64()
+	last := 0
+	for i, p := range chances {
+		if p == 0 {
+			continue
+		}
+		if pick < p {
+			return i
+		}
+		pick -= p
+		last = i
+	}
+	return last
+}
@@ -0,0 +1,407 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// Deck is a persistent deck of cards. Pile holds the undrawn cards with the
+// top card first; Drawn holds the cards drawn since the last shuffle, in the
+// order they came out.
+type Deck struct {
+	Type       string    `json:"type"`
+	Cards      []string  `json:"cards"`
+	Pile       []string  `json:"pile"`
+	Drawn      []string  `json:"drawn"`
+	Reversals  bool      `json:"reversals,omitempty"`
+	ShuffledAt time.Time `json:"shuffled_at"`
+}
+
+var deckCmd = &cobra.Command{
+	Use:   "deck",
+	Short: "Draw cards without replacement from decks that persist between runs",
+	Long: `Keep named decks of cards and draw from them across invocations:
+
+  roll deck create poker
+  roll deck draw poker -n 5
+  roll deck shuffle poker
+
+Decks are a standard 52-card deck (--jokers adds two), a 78-card tarot deck
+(--reversals draws cards upright or reversed) or any list of cards in a
+file, one per line. Drawn cards stay out until the deck is shuffled.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		decks, err := loadDecks()
+		if err != nil {
+			log.Fatal("Failed to load decks:", err)
+		}
+		if len(decks) == 0 {
+			if !quiet {
+				fmt.Println("No decks yet. Create one with: roll deck create [name]")
+			}
+			return
+		}
+
+		names := make([]string, 0, len(decks))
+		for name := range decks {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if !quiet && !a11y {
+			fmt.Fprintln(w, "NAME\tTYPE\tLEFT\tDRAWN")
+		}
+		for _, name := range names {
+			deck := decks[name]
+			switch {
+			case quiet:
+				fmt.Println(name)
+			case a11y:
+				fmt.Printf("%s: %s deck, %d of %d cards left.\n", name, deck.Type, len(deck.Pile), len(deck.Cards))
+			default:
+				fmt.Fprintf(w, "%s\t%s\t%d/%d\t%d\n", name, deck.Type, len(deck.Pile), len(deck.Cards), len(deck.Drawn))
+			}
+		}
+		w.Flush()
+	},
+}
+
+var deckCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create and shuffle a deck",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kind, _ := cmd.Flags().GetString("type")
+		file, _ := cmd.Flags().GetString("file")
+		jokers, _ := cmd.Flags().GetBool("jokers")
+		reversals, _ := cmd.Flags().GetBool("reversals")
+		force, _ := cmd.Flags().GetBool("force")
+		name := args[0]
+
+		deck := Deck{Type: kind, Reversals: reversals}
+		switch {
+		case file != "":
+			cards, err := readLines(file)
+			if err != nil {
+				log.Fatal("Failed to read deck file:", err)
+			}
+			if len(cards) == 0 {
+				log.Fatal("The deck file has no cards")
+			}
+			deck.Type, deck.Cards = "custom", cards
+		case kind == "standard":
+			deck.Cards = standardDeck(jokers)
+		case kind == "tarot":
+			deck.Cards = tarotDeck()
+		default:
+			log.Fatalf("Unknown deck type '%s'; use standard or tarot, or --file", kind)
+		}
+
+		if _, err := loadDeck(name); err == nil && !force {
+			log.Fatalf("Deck '%s' already exists; shuffle it, or use --force to replace it", name)
+		}
+		if verbose {
+			printProvenance()
+		}
+		deck.shuffle(false)
+		if err := saveDeck(name, &deck); err != nil {
+			log.Fatal("Failed to save deck:", err)
+		}
+
+		fmt.Printf("Created %s deck '%s' with %d cards, shuffled\n", deck.Type, name, len(deck.Cards))
+	},
+}
+
+var deckDrawCmd = &cobra.Command{
+	Use:   "draw [name]",
+	Short: "Draw cards from the top of a deck",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		n, _ := cmd.Flags().GetInt("count")
+		name := args[0]
+
+		if n < 1 {
+			log.Fatal("-n must be at least 1")
+		}
+		if verbose {
+			printProvenance()
+		}
+
+		var drawn []string
+		var left int
+		err := updateDeck(name, func(deck *Deck) error {
+			if n > len(deck.Pile) {
+				return fmt.Errorf("only %d cards left; shuffle the deck to put drawn cards back", len(deck.Pile))
+			}
+			drawn = append([]string(nil), deck.Pile[:n]...)
+			if deck.Reversals {
+				for i := range drawn {
+					if rng.Intn(2) == 1 {
+						drawn[i] += " (reversed)"
+					}
+				}
+			}
+			deck.Pile = deck.Pile[n:]
+			deck.Drawn = append(deck.Drawn, drawn...)
+			left = len(deck.Pile)
+			return nil
+		})
+		if err != nil {
+			log.Fatal("Failed to draw: ", err)
+		}
+
+		if quiet {
+			fmt.Println(strings.Join(drawn, "\n"))
+			return
+		}
+		if a11y {
+			fmt.Printf("Drew %s from %s: %s. %d cards left.\n", plural(n, "card", "cards"), name, strings.Join(drawn, ", "), left)
+			return
+		}
+		fmt.Printf("\n🃏 Drawing %s from '%s'...\n", plural(n, "card", "cards"), name)
+		for _, card := range drawn {
+			fmt.Printf("  %s\n", card)
+		}
+		fmt.Printf("%d cards left\n", left)
+	},
+}
+
+var deckShuffleCmd = &cobra.Command{
+	Use:   "shuffle [name]",
+	Short: "Put drawn cards back and shuffle the deck",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		remaining, _ := cmd.Flags().GetBool("remaining")
+		name := args[0]
+
+		if verbose {
+			printProvenance()
+		}
+		var left int
+		err := updateDeck(name, func(deck *Deck) error {
+			deck.shuffle(remaining)
+			left = len(deck.Pile)
+			return nil
+		})
+		if err != nil {
+			log.Fatal("Failed to shuffle: ", err)
+		}
+
+		if remaining {
+			fmt.Printf("Shuffled the %d cards left in '%s'\n", left, name)
+		} else {
+			fmt.Printf("Shuffled all %d cards back into '%s'\n", left, name)
+		}
+	},
+}
+
+var deckShowCmd = &cobra.Command{
+	Use:   "show [name]",
+	Short: "Show how many cards are left and which have been drawn",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		deck, err := loadDeck(name)
+		if err != nil {
+			log.Fatal("Failed to load deck: ", err)
+		}
+
+		if quiet {
+			fmt.Println(len(deck.Pile))
+			return
+		}
+		if a11y {
+			fmt.Printf("%s deck %s: %d of %d cards left, shuffled %s.\n",
+				strings.ToUpper(deck.Type[:1])+deck.Type[1:], name, len(deck.Pile), len(deck.Cards), since(deck.ShuffledAt))
+			if len(deck.Drawn) > 0 {
+				fmt.Printf("Drawn since then: %s.\n", strings.Join(deck.Drawn, ", "))
+			}
+			return
+		}
+		fmt.Printf("Deck '%s' (%s):\n", name, deck.Type)
+		fmt.Printf("  Cards left: %d of %d\n", len(deck.Pile), len(deck.Cards))
+		fmt.Printf("  Shuffled: %s\n", formatTime(deck.ShuffledAt))
+		if len(deck.Drawn) > 0 {
+			fmt.Printf("\nDrawn since the shuffle:\n")
+			for _, card := range deck.Drawn {
+				fmt.Printf("  %s\n", card)
+			}
+		}
+	},
+}
+
+var deckDeleteCmd = &cobra.Command{
+	Use:   "delete [name]",
+	Short: "Delete a deck",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		if _, err := loadDeck(name); err != nil {
+			log.Fatal("Failed to load deck: ", err)
+		}
+		err := db.Update(func(tx *bolt.Tx) error {
+			return tx.Bucket([]byte("decks")).Delete([]byte(name))
+		})
+		if err != nil {
+			log.Fatal("Failed to delete deck:", err)
+		}
+		fmt.Printf("Deleted deck '%s'\n", name)
+	},
+}
+
+func init() {
+	deckCmd.AddCommand(deckCreateCmd)
+	deckCmd.AddCommand(deckDrawCmd)
+	deckCmd.AddCommand(deckShuffleCmd)
+	deckCmd.AddCommand(deckShowCmd)
+	deckCmd.AddCommand(deckDeleteCmd)
+
+	deckCreateCmd.Flags().String("type", "standard", "Deck to create: standard or tarot")
+	deckCreateCmd.Flags().String("file", "", "Create a custom deck from this file, one card per line")
+	deckCreateCmd.Flags().Bool("jokers", false, "Add two jokers to a standard deck")
+	deckCreateCmd.Flags().Bool("reversals", false, "Draw each card upright or reversed, as in tarot")
+	deckCreateCmd.Flags().Bool("force", false, "Replace a deck that already exists")
+	deckCreateCmd.RegisterFlagCompletionFunc("type", completeFlag(func() []string { return []string{"standard", "tarot"} }))
+	deckDrawCmd.Flags().IntP("count", "n", 1, "Number of cards to draw")
+	deckShuffleCmd.Flags().Bool("remaining", false, "Shuffle only the undrawn cards, leaving drawn ones out")
+
+	for _, cmd := range []*cobra.Command{deckDrawCmd, deckShuffleCmd, deckShowCmd, deckDeleteCmd} {
+		cmd.ValidArgsFunction = completeArgs(deckNames)
+	}
+}
+
+// shuffle puts the drawn cards back, unless only the remaining pile is to be
+// shuffled, and shuffles the pile
+func (d *Deck) shuffle(remaining bool) {
+	if !remaining {
+		d.Pile = append([]string(nil), d.Cards...)
+		d.Drawn = nil
+	}
+	rng.Shuffle(len(d.Pile), func(i, j int) { d.Pile[i], d.Pile[j] = d.Pile[j], d.Pile[i] })
+	d.ShuffledAt = time.Now().UTC()
+}
+
+func standardDeck(jokers bool) []string {
+	var cards []string
+	for _, suit := range []string{"Spades", "Hearts", "Diamonds", "Clubs"} {
+		for _, rank := range []string{"Ace", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine", "Ten", "Jack", "Queen", "King"} {
+			cards = append(cards, rank+" of "+suit)
+		}
+	}
+	if jokers {
+		cards = append(cards, "Red Joker", "Black Joker")
+	}
+	return cards
+}
+
+func tarotDeck() []string {
+	cards := []string{
+		"The Fool", "The Magician", "The High Priestess", "The Empress", "The Emperor",
+		"The Hierophant", "The Lovers", "The Chariot", "Strength", "The Hermit",
+		"Wheel of Fortune", "Justice", "The Hanged Man", "Death", "Temperance",
+		"The Devil", "The Tower", "The Star", "The Moon", "The Sun", "Judgement", "The World",
+	}
+	for _, suit := range []string{"Wands", "Cups", "Swords", "Pentacles"} {
+		for _, rank := range []string{"Ace", "Two", "Three", "Four", "Five", "Six", "Seven", "Eight", "Nine", "Ten", "Page", "Knight", "Queen", "King"} {
+			cards = append(cards, rank+" of "+suit)
+		}
+	}
+	return cards
+}
+
+func loadDeck(name string) (*Deck, error) {
+	var deck Deck
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("decks"))
+		if b == nil {
+			return fmt.Errorf("no deck named '%s'", name)
+		}
+		data := b.Get([]byte(name))
+		if data == nil {
+			return fmt.Errorf("no deck named '%s'", name)
+		}
+		return json.Unmarshal(data, &deck)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &deck, nil
+}
+
+func saveDeck(name string, deck *Deck) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("decks"))
+		if err != nil {
+			return err
+		}
+		data, err := json.Marshal(deck)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(name), data)
+	})
+}
+
+// updateDeck loads a deck, changes it with fn and saves it in one
+// transaction, so concurrent draws never hand out the same card
+func updateDeck(name string, fn func(deck *Deck) error) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("decks"))
+		if b == nil || b.Get([]byte(name)) == nil {
+			return fmt.Errorf("no deck named '%s'", name)
+		}
+		var deck Deck
+		if err := json.Unmarshal(b.Get([]byte(name)), &deck); err != nil {
+			return err
+		}
+		if err := fn(&deck); err != nil {
+			return err
+		}
+		data, err := json.Marshal(deck)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(name), data)
+	})
+}
+
+func loadDecks() (map[string]Deck, error) {
+	decks := map[string]Deck{}
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("decks"))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var deck Deck
+			if err := json.Unmarshal(v, &deck); err != nil {
+				return err
+			}
+			decks[string(k)] = deck
+			return nil
+		})
+	})
+	return decks, err
+}
+
+func deckNames() []string {
+	decks, err := loadDecks()
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for name := range decks {
+		names = append(names, name)
+	}
+	return names
+}
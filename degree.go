@@ -0,0 +1,45 @@
+package main
+
+import "fmt"
+
+// Degree labels rolls whose margin falls within a range. The margin is the
+// effective chance minus the roll, so successes have a margin of 0 or more.
+type Degree struct {
+	Label string `toml:"label"`
+	Min   *int   `toml:"min"`
+	Max   *int   `toml:"max"`
+}
+
+// matchDegree returns the label of the first degree containing margin
+func matchDegree(config *Config, margin int) string {
+	for _, degree := range config.Degrees {
+		if degree.Min != nil && margin < *degree.Min {
+			continue
+		}
+		if degree.Max != nil && margin > *degree.Max {
+			continue
+		}
+		return degree.Label
+	}
+	return ""
+}
+
+// validateDegrees checks the degree ranges of a config
+func validateDegrees(config *Config) []error {
+	var errs []error
+	if len(config.Degrees) > 0 && len(config.Checks) > 0 {
+		errs = append(errs, fmt.Errorf("Degrees cannot be used with checks"))
+	}
+	for i, degree := range config.Degrees {
+		if degree.Label == "" {
+			errs = append(errs, fmt.Errorf("Degree %d needs a label", i+1))
+		}
+		if degree.Min == nil && degree.Max == nil {
+			errs = append(errs, fmt.Errorf("Degree '%s' needs a min or max margin", degree.Label))
+		}
+		if degree.Min != nil && degree.Max != nil && *degree.Min > *degree.Max {
+			errs = append(errs, fmt.Errorf("Degree '%s' min must not exceed max", degree.Label))
+		}
+	}
+	return errs
+}
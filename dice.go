@@ -0,0 +1,353 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// maxRepetitions bounds NxEXPR so a typo can't flood the terminal
+const maxRepetitions = 1000
+
+// splitRepetition splits "6x(d6)" or "6xd6" into a count and the expression
+// to repeat. Expressions without a prefix are rolled once.
+func splitRepetition(expr string) (int, string, error) {
+	prefix, rest, ok := strings.Cut(strings.TrimSpace(expr), "x")
+	if !ok {
+		return 1, expr, nil
+	}
+	count, err := strconv.Atoi(prefix)
+	if err != nil {
+		return 1, expr, nil
+	}
+	if count < 1 || count > maxRepetitions {
+		return 0, "", fmt.Errorf("Repetitions must be between 1 and %d", maxRepetitions)
+	}
+
+	rest = strings.TrimSpace(rest)
+	if strings.HasPrefix(rest, "(") && strings.HasSuffix(rest, ")") {
+		rest = rest[1 : len(rest)-1]
+	}
+	return count, rest, nil
+}
+
+// rollAdvantage rolls an expression once, or with advantage (adv > 0) or
+// disadvantage (adv < 0) twice, keeping the higher or lower total. The
+// second result is the dropped roll, if there was one.
+func rollAdvantage(expr *DiceExpr, adv int) (DiceRoll, *DiceRoll) {
+	kept := expr.Roll()
+	if adv == 0 {
+		return kept, nil
+	}
+	other := expr.Roll()
+	if (adv > 0 && other.Total > kept.Total) || (adv < 0 && other.Total < kept.Total) {
+		kept, other = other, kept
+	}
+	return kept, &other
+}
+
+// advantageName describes an advantage mode for display
+func advantageName(adv int) string {
+	switch {
+	case adv > 0:
+		return " with advantage"
+	case adv < 0:
+		return " with disadvantage"
+	}
+	return ""
+}
+
+// printDiceRoll prints a single roll of an expression, with the individual
+// dice when there is more than one and the dropped roll under advantage
+func printDiceRoll(name string, roll DiceRoll, dropped *DiceRoll, shift int) {
+	expr := roll.Expr
+	if verbose {
+		printProvenance()
+		for _, r := range []*DiceRoll{&roll, dropped} {
+			if r == nil {
+				continue
+			}
+			for i, term := range expr.Terms {
+				for _, die := range r.Dice[i] {
+					if term.Percentile {
+						fmt.Printf("Raw draw: %d of [0, 10), tens = %02d\n", die.Faces[0]%100/10, die.Faces[0]%100)
+						fmt.Printf("Raw draw: %d of [0, 10), ones = %d\n", die.Faces[1], die.Faces[1])
+						continue
+					}
+					for _, face := range append(append([]int(nil), die.Rerolled...), die.Faces...) {
+						if term.Fudge {
+							fmt.Printf("Raw draw: %d of [0, 3), roll = %+d\n", face+1, face)
+							continue
+						}
+						if term.Digits > 0 {
+							for _, digit := range strconv.Itoa(face) {
+								fmt.Printf("Raw draw: %d of [0, %d), digit = %c\n", digit-'1', term.Sides, digit)
+							}
+							continue
+						}
+						fmt.Printf("Raw draw: %d of [0, %d), roll = %d\n", face-1, term.Sides, face)
+					}
+				}
+			}
+		}
+	}
+
+	if quiet {
+		fmt.Println(roll.Total + shift)
+		return
+	}
+
+	if a11y {
+		if shift != 0 {
+			fmt.Printf("Rolling %s shifted by %d. Result: %d.\n", name, shift, roll.Total+shift)
+		} else {
+			fmt.Printf("Rolling %s. Result: %d.\n", name, roll.Total)
+		}
+		if dropped != nil {
+			fmt.Printf("Kept %d, dropped %d.\n", roll.Total, dropped.Total)
+		}
+		if values := roll.Values(); !expr.SingleDie() {
+			fmt.Printf("The dice showed %s.\n", listAnd(values))
+			if rerolled := roll.RerolledValues(); len(rerolled) > 0 {
+				fmt.Printf("Rerolled %s.\n", listAnd(rerolled))
+			}
+			if dropped := roll.DroppedValues(); len(dropped) > 0 {
+				fmt.Printf("Dropped %s.\n", listAnd(dropped))
+			}
+		} else if shift != 0 {
+			fmt.Printf("The die showed %d.\n", values[0])
+		}
+		fmt.Printf("Possible results range from %d to %d.\n", expr.Min()+shift, expr.Max()+shift)
+		return
+	}
+
+	fmt.Printf("\n🎲 Rolling %s...\n", name)
+	if dropped != nil {
+		fmt.Printf("Rolls: %d and %d, keeping %d\n", roll.Total, dropped.Total, roll.Total)
+	}
+	if expr.SingleDie() {
+		fmt.Printf("Roll: %d\n", roll.Total)
+	} else {
+		fmt.Printf("Dice: %s\n", roll.Detail())
+		fmt.Printf("Total: %d\n", roll.Total)
+	}
+
+	if shift != 0 {
+		result := roll.Total + shift
+		fmt.Printf("Shifted result: %d (roll + %d)\n", result, shift)
+		fmt.Printf("\nRange for %s with shift: %d-%d\n", name, expr.Min()+shift, expr.Max()+shift)
+	} else {
+		fmt.Printf("\nStandard range for %s: %d-%d\n", name, expr.Min(), expr.Max())
+	}
+}
+
+// listAnd joins numbers for reading aloud, e.g. "3, 5 and 1"
+func listAnd(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
+
+// rollRepeated rolls the same expression count times and prints each total
+// with a summary line
+func rollRepeated(label string, expr *DiceExpr, adv, shift, count int) {
+	rolls := make([]DiceRoll, count)
+	results := make([]int, count)
+	for i := range results {
+		rolls[i], _ = rollAdvantage(expr, adv)
+		results[i] = rolls[i].Total + shift
+		if verbose {
+			if i == 0 {
+				printProvenance()
+			}
+			fmt.Printf("Roll %d dice: %s\n", i+1, rolls[i].Detail())
+		}
+	}
+
+	sorted := append([]int(nil), results...)
+	sort.Ints(sorted)
+	total := 0
+	for _, r := range results {
+		total += r
+	}
+
+	if quiet {
+		fmt.Println(strings.Trim(fmt.Sprint(results), "[]"))
+		return
+	}
+
+	name := fmt.Sprintf("%dx %s%s", count, expr, advantageName(adv))
+	if shift != 0 {
+		name += fmt.Sprintf(" shifted by %d", shift)
+	}
+	if label != "" {
+		name = fmt.Sprintf("%s (%s)", label, name)
+	}
+
+	if a11y {
+		fmt.Printf("Rolling %s.\n", name)
+		for i, r := range results {
+			fmt.Printf("Roll %d: %d.\n", i+1, r)
+		}
+		fmt.Printf("Total %d, lowest %d, highest %d.\n", total, sorted[0], sorted[len(sorted)-1])
+		return
+	}
+
+	fmt.Printf("\n🎲 Rolling %s...\n", name)
+	for i, r := range results {
+		if expr.SingleDie() {
+			fmt.Printf("  %d: %d\n", i+1, r)
+		} else {
+			fmt.Printf("  %d: %d  %s\n", i+1, r, rolls[i].Detail())
+		}
+	}
+	fmt.Printf("\nTotal: %d | Min: %d | Max: %d\n", total, sorted[0], sorted[len(sorted)-1])
+	fmt.Printf("Sorted: %s\n", strings.Trim(fmt.Sprint(sorted), "[]"))
+}
+
+// Distribution maps each possible result to its probability
+type Distribution map[int]float64
+
+// Advantage returns the distribution of the higher (adv > 0) or lower
+// (adv < 0) of two independent results
+func (d Distribution) Advantage(adv int) Distribution {
+	if adv == 0 {
+		return d
+	}
+
+	best := Distribution{}
+	below := 0.0
+	for _, v := range d.Values() {
+		p := d[v]
+		if adv > 0 {
+			// P(max = v) = P(X <= v)² - P(X < v)²
+			best[v] = (below+p)*(below+p) - below*below
+		} else {
+			// P(min = v) = P(X >= v)² - P(X > v)²
+			above := 1 - below
+			best[v] = above*above - (above-p)*(above-p)
+		}
+		below += p
+	}
+	return best
+}
+
+// Values returns the possible results in ascending order
+func (d Distribution) Values() []int {
+	values := make([]int, 0, len(d))
+	for v := range d {
+		values = append(values, v)
+	}
+	sort.Ints(values)
+	return values
+}
+
+// Mean returns the expected result
+func (d Distribution) Mean() float64 {
+	mean := 0.0
+	for v, p := range d {
+		mean += float64(v) * p
+	}
+	return mean
+}
+
+// AtLeast returns the probability of a result of dc or more
+func (d Distribution) AtLeast(dc int) float64 {
+	chance := 0.0
+	for v, p := range d {
+		if v >= dc {
+			chance += p
+		}
+	}
+	return chance
+}
+
+// printDiceOdds prints the exact distribution of a roll
+func printDiceOdds(name string, dist Distribution) {
+	values := dist.Values()
+
+	if quiet {
+		fmt.Println(formatFloat(dist.Mean(), settings.Precision))
+		return
+	}
+
+	fmt.Printf("Odds for %s:\n", name)
+	fmt.Printf("  Mean: %s\n", formatFloat(dist.Mean(), settings.Precision))
+	fmt.Printf("  Min: %d | Max: %d\n", values[0], values[len(values)-1])
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Result\tChance\tAt least\t")
+	for _, v := range values {
+		fmt.Fprintf(w, "%d\t%s\t%s\t\n", v, formatPercent(dist[v]), formatPercent(dist.AtLeast(v)))
+	}
+	w.Flush()
+}
+
+// printDCChances prints the chance of meeting each target number, computed
+// from the distribution and, when trials > 0, also by rolling sample that
+// many times as a sanity check
+func printDCChances(name string, dist Distribution, dcs []int, sample func() int, trials int) {
+	hits := make([]int, len(dcs))
+	for i := 0; i < trials; i++ {
+		result := sample()
+		for j, dc := range dcs {
+			if result >= dc {
+				hits[j]++
+			}
+		}
+	}
+	simulated := func(j int) string {
+		return formatPercent(float64(hits[j]) / float64(trials))
+	}
+
+	if quiet {
+		for j, dc := range dcs {
+			if trials > 0 {
+				fmt.Printf("%d\t%s\t%s\n", dc, formatPercent(dist.AtLeast(dc)), simulated(j))
+			} else {
+				fmt.Printf("%d\t%s\n", dc, formatPercent(dist.AtLeast(dc)))
+			}
+		}
+		return
+	}
+
+	if a11y {
+		for j, dc := range dcs {
+			fmt.Printf("Chance of %d or more on %s: %s.", dc, name, formatPercent(dist.AtLeast(dc)))
+			if trials > 0 {
+				fmt.Printf(" Simulated over %s rolls: %s.", formatCount(trials), simulated(j))
+			}
+			fmt.Println()
+		}
+		return
+	}
+
+	fmt.Printf("Target numbers for %s:\n", name)
+	if trials > 0 {
+		fmt.Printf("  Simulated with %s rolls\n", formatCount(trials))
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight)
+	if trials > 0 {
+		fmt.Fprintln(w, "DC\tExact\tSimulated\t")
+	} else {
+		fmt.Fprintln(w, "DC\tExact\t")
+	}
+	for j, dc := range dcs {
+		if trials > 0 {
+			fmt.Fprintf(w, "%d\t%s\t%s\t\n", dc, formatPercent(dist.AtLeast(dc)), simulated(j))
+		} else {
+			fmt.Fprintf(w, "%d\t%s\t\n", dc, formatPercent(dist.AtLeast(dc)))
+		}
+	}
+	w.Flush()
+}
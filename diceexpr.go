@@ -0,0 +1,776 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Limits that keep a typo like 1000000d6 from hanging the terminal
+const (
+	maxDice  = 1000
+	maxSides = 1000000
+
+	// maxDigits is the most digits a digit die like d666 may have
+	maxDigits = 6
+
+	// maxConvolution bounds the work for exact odds, roughly the number of
+	// multiply-adds needed to convolve every die
+	maxConvolution = 1e9
+
+	// defaultExplodeCap is how many extra rolls one exploding die may add
+	defaultExplodeCap = 10
+)
+
+// DiceExpr is a parsed dice expression such as "3d6+2": a sum of dice groups
+// and constants
+type DiceExpr struct {
+	Terms []DiceTerm
+
+	// ExplodeCap limits the extra rolls of each exploding die
+	ExplodeCap int
+}
+
+// DiceTerm is one signed part of an expression: Count dice with Sides faces,
+// or the constant Value when Sides is 0. Exploding dice (3d6!) roll again
+// and add whenever they show their highest face. Reroll replaces a first
+// face matching RerollOp ("=", "<" or ">") RerollN, once or, with
+// RerollAll, until it no longer matches. Select is a keep or drop rule (kh,
+// kl, dh or dl) applied to SelectN dice, as in 4d6kh3. Fudge dice (4dF)
+// have three sides showing -1, 0 and +1. Digit dice (d66) roll Digits dice
+// of Sides faces and read them as the digits of one number. Percentile dice
+// (d%) roll a tens die and a ones die, with 00 and 0 reading as 100.
+type DiceTerm struct {
+	Sign       int
+	Count      int
+	Sides      int
+	Value      int
+	Fudge      bool
+	Digits     int
+	Percentile bool
+	Explode    bool
+	Reroll     bool
+	RerollAll  bool
+	RerollOp   string
+	RerollN    int
+	Select     string
+	SelectN    int
+}
+
+// DiceRoll is the outcome of rolling an expression. Dice holds the dice
+// rolled for each term, and is nil for constants.
+type DiceRoll struct {
+	Expr  *DiceExpr
+	Dice  [][]Die
+	Total int
+}
+
+// Die is one rolled die. Faces holds the first face followed by any
+// explosion rolls, and Rerolled the faces a reroll replaced, in order.
+// Dropped dice do not count towards the total.
+type Die struct {
+	Faces    []int
+	Rerolled []int
+	Dropped  bool
+}
+
+// Value is what the die adds to the total
+func (d Die) Value() int {
+	value := 0
+	for _, face := range d.Faces {
+		value += face
+	}
+	return value
+}
+
+// parseDice parses standard dice notation: terms like 3d6, d20 or 5 joined
+// by + and -, with whitespace ignored
+func parseDice(src string) (*DiceExpr, error) {
+	s := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, src)
+	if s == "" {
+		return nil, fmt.Errorf("empty dice expression")
+	}
+
+	expr := &DiceExpr{ExplodeCap: defaultExplodeCap}
+	for i := 0; i < len(s); {
+		sign := 1
+		switch s[i] {
+		case '+':
+			i++
+		case '-':
+			sign = -1
+			i++
+		default:
+			if i > 0 {
+				return nil, fmt.Errorf("expected + or - at %q", s[i:])
+			}
+		}
+
+		term, n, err := parseDiceTerm(s[i:])
+		if err != nil {
+			return nil, err
+		}
+		term.Sign = sign
+		expr.Terms = append(expr.Terms, term)
+		i += n
+	}
+
+	for _, term := range expr.Terms {
+		if term.Sides > 0 {
+			return expr, nil
+		}
+	}
+	return nil, fmt.Errorf("%q has no dice to roll", src)
+}
+
+// parseDiceTerm parses one term from the start of s, returning it and the
+// number of bytes used
+func parseDiceTerm(s string) (DiceTerm, int, error) {
+	i := 0
+	for i < len(s) && unicode.IsDigit(rune(s[i])) {
+		i++
+	}
+	digits := s[:i]
+
+	if i == len(s) || (s[i] != 'd' && s[i] != 'D') {
+		if digits == "" {
+			return DiceTerm{}, 0, fmt.Errorf("expected a number or dice like 3d6 at %q", s)
+		}
+		value, err := strconv.Atoi(digits)
+		if err != nil {
+			return DiceTerm{}, 0, fmt.Errorf("invalid number %q", digits)
+		}
+		return DiceTerm{Value: value}, i, nil
+	}
+
+	count := 1
+	if digits != "" {
+		n, err := strconv.Atoi(digits)
+		if err != nil || n < 1 || n > maxDice {
+			return DiceTerm{}, 0, fmt.Errorf("dice count must be between 1 and %d", maxDice)
+		}
+		count = n
+	}
+
+	i++
+	var term DiceTerm
+	if i < len(s) && s[i] == '%' {
+		term = DiceTerm{Count: count, Sides: 100, Percentile: true}
+		i++
+		if i < len(s) && (s[i] == '!' || s[i] == 'r' || s[i] == 'R') {
+			return DiceTerm{}, 0, fmt.Errorf("percentile dice cannot explode or reroll")
+		}
+	} else if i < len(s) && (s[i] == 'F' || s[i] == 'f') {
+		term = DiceTerm{Count: count, Sides: 3, Fudge: true}
+		i++
+		if i < len(s) && (s[i] == '!' || s[i] == 'r' || s[i] == 'R') {
+			return DiceTerm{}, 0, fmt.Errorf("fudge dice cannot explode or reroll")
+		}
+	} else {
+		start := i
+		for i < len(s) && unicode.IsDigit(rune(s[i])) {
+			i++
+		}
+		sides, err := strconv.Atoi(s[start:i])
+		if err != nil || sides < 1 || sides > maxSides {
+			return DiceTerm{}, 0, fmt.Errorf("dice sides must be between 1 and %d", maxSides)
+		}
+		term = DiceTerm{Count: count, Sides: sides}
+
+		// Repeated 6s, as in d66 or d666, are a digit die; other sides such as
+		// d22 or d88 keep their plain meaning
+		if digit := s[start]; i-start > 1 && digit == '6' && strings.Count(s[start:i], "6") == i-start {
+			if i-start > maxDigits {
+				return DiceTerm{}, 0, fmt.Errorf("digit dice can have at most %d digits", maxDigits)
+			}
+			term = DiceTerm{Count: count, Sides: int(digit - '0'), Digits: i - start}
+			if i < len(s) && (s[i] == '!' || s[i] == 'r' || s[i] == 'R') {
+				return DiceTerm{}, 0, fmt.Errorf("digit dice cannot explode or reroll")
+			}
+		}
+	}
+	sides := term.Sides
+
+	if i < len(s) && s[i] == '!' {
+		if sides == 1 {
+			return DiceTerm{}, 0, fmt.Errorf("d1 would explode on every roll")
+		}
+		term.Explode = true
+		i++
+	}
+
+	// Reroll rule: r1 rerolls 1s once, rr<3 rerolls until 3 or more
+	if i < len(s) && (s[i] == 'r' || s[i] == 'R') {
+		i++
+		if i < len(s) && (s[i] == 'r' || s[i] == 'R') {
+			term.RerollAll = true
+			i++
+		}
+		term.Reroll, term.RerollOp = true, "="
+		if i < len(s) && (s[i] == '<' || s[i] == '>' || s[i] == '=') {
+			term.RerollOp = s[i : i+1]
+			i++
+		}
+		start := i
+		for i < len(s) && unicode.IsDigit(rune(s[i])) {
+			i++
+		}
+		if i == start {
+			return DiceTerm{}, 0, fmt.Errorf("reroll needs a number, as in r1 or r<3")
+		}
+		term.RerollN, _ = strconv.Atoi(s[start:i])
+
+		matching := 0
+		for face := 1; face <= sides; face++ {
+			if term.rerolls(face) {
+				matching++
+			}
+		}
+		if matching == sides {
+			return DiceTerm{}, 0, fmt.Errorf("%s would reroll every face", term)
+		}
+	}
+
+	// Keep or drop rule: kh3, kl1, dh1, dl1 (k alone means kh)
+	if i < len(s) && (s[i] == 'k' || s[i] == 'K' || s[i] == 'd' || s[i] == 'D') {
+		op := strings.ToLower(s[i : i+1])
+		i++
+		switch {
+		case i < len(s) && (s[i] == 'h' || s[i] == 'H' || s[i] == 'l' || s[i] == 'L'):
+			op += strings.ToLower(s[i : i+1])
+			i++
+		case op == "k":
+			op = "kh"
+		default:
+			return DiceTerm{}, 0, fmt.Errorf("expected dh or dl at %q", s[i-1:])
+		}
+
+		start := i
+		for i < len(s) && unicode.IsDigit(rune(s[i])) {
+			i++
+		}
+		n := 1
+		if i > start {
+			n, _ = strconv.Atoi(s[start:i])
+		}
+
+		lo, hi := 1, count
+		if op[0] == 'd' {
+			lo, hi = 0, count-1
+		}
+		if n < lo || n > hi {
+			return DiceTerm{}, 0, fmt.Errorf("%s%d needs between %d and %d for %d dice", op, n, lo, hi, count)
+		}
+		term.Select, term.SelectN = op, n
+	}
+	return term, i, nil
+}
+
+// keep returns how many dice of the term count and whether they are the
+// highest or the lowest
+func (t DiceTerm) keep() (int, bool) {
+	switch t.Select {
+	case "kl":
+		return t.SelectN, false
+	case "dl":
+		return t.Count - t.SelectN, true
+	case "dh":
+		return t.Count - t.SelectN, false
+	case "kh":
+		return t.SelectN, true
+	}
+	return t.Count, true
+}
+
+// String writes the expression back in normalised notation
+func (e *DiceExpr) String() string {
+	var b strings.Builder
+	for i, term := range e.Terms {
+		if term.Sign < 0 {
+			b.WriteString("-")
+		} else if i > 0 {
+			b.WriteString("+")
+		}
+		b.WriteString(term.String())
+	}
+	return b.String()
+}
+
+func (t DiceTerm) String() string {
+	if t.Sides == 0 {
+		return strconv.Itoa(t.Value)
+	}
+	s := fmt.Sprintf("d%d", t.Sides)
+	if t.Fudge {
+		s = "dF"
+	}
+	if t.Digits > 0 {
+		s = "d" + strings.Repeat(strconv.Itoa(t.Sides), t.Digits)
+	}
+	if t.Percentile {
+		s = "d%"
+	}
+	if t.Count > 1 {
+		s = strconv.Itoa(t.Count) + s
+	}
+	if t.Explode {
+		s += "!"
+	}
+	if t.Reroll {
+		s += "r"
+		if t.RerollAll {
+			s += "r"
+		}
+		if t.RerollOp != "=" {
+			s += t.RerollOp
+		}
+		s += strconv.Itoa(t.RerollN)
+	}
+	if t.Select != "" {
+		s += t.Select + strconv.Itoa(t.SelectN)
+	}
+	return s
+}
+
+// SingleDie reports whether the expression is just one die, like d20
+func (e *DiceExpr) SingleDie() bool {
+	if len(e.Terms) != 1 {
+		return false
+	}
+	t := e.Terms[0]
+	return t.Sign > 0 && t.Sides > 0 && t.Count == 1 && !t.Fudge && !t.Percentile && !t.Explode && !t.Reroll && t.Select == ""
+}
+
+// Min returns the lowest possible total
+func (e *DiceExpr) Min() int {
+	total := 0
+	for _, term := range e.Terms {
+		lo, hi := term.bounds(e.ExplodeCap)
+		if term.Sign > 0 {
+			total += lo
+		} else {
+			total -= hi
+		}
+	}
+	return total
+}
+
+// Max returns the highest possible total
+func (e *DiceExpr) Max() int {
+	total := 0
+	for _, term := range e.Terms {
+		lo, hi := term.bounds(e.ExplodeCap)
+		if term.Sign > 0 {
+			total += hi
+		} else {
+			total -= lo
+		}
+	}
+	return total
+}
+
+// bounds returns the smallest and largest value of the term before its sign
+// is applied
+func (t DiceTerm) bounds(explodeCap int) (int, int) {
+	if t.Sides == 0 {
+		return t.Value, t.Value
+	}
+	faces := t.faceDistribution().Values()
+	lo, hi := faces[0], faces[len(faces)-1]
+	if t.Explode && hi == t.Sides {
+		hi *= explodeCap + 1
+	}
+	kept, _ := t.keep()
+	return kept * lo, kept * hi
+}
+
+// rerolls reports whether a first face is rerolled
+func (t DiceTerm) rerolls(face int) bool {
+	if !t.Reroll {
+		return false
+	}
+	switch t.RerollOp {
+	case "<":
+		return face < t.RerollN
+	case ">":
+		return face > t.RerollN
+	}
+	return face == t.RerollN
+}
+
+// faceDistribution returns the distribution of a die's first face once
+// rerolls are done, before any explosion
+func (t DiceTerm) faceDistribution() Distribution {
+	if t.Fudge {
+		return Distribution{-1: 1.0 / 3, 0: 1.0 / 3, 1: 1.0 / 3}
+	}
+	if t.Digits > 0 {
+		dist := Distribution{0: 1}
+		for d := 0; d < t.Digits; d++ {
+			next := Distribution{}
+			for v, p := range dist {
+				for face := 1; face <= t.Sides; face++ {
+					next[v*10+face] += p / float64(t.Sides)
+				}
+			}
+			dist = next
+		}
+		return dist
+	}
+
+	matching := 0
+	for face := 1; face <= t.Sides; face++ {
+		if t.rerolls(face) {
+			matching++
+		}
+	}
+
+	dist := Distribution{}
+	p := 1 / float64(t.Sides)
+	for face := 1; face <= t.Sides; face++ {
+		switch {
+		case t.RerollAll:
+			// Rerolling until it sticks is uniform over the faces that stick
+			if !t.rerolls(face) {
+				dist[face] = 1 / float64(t.Sides-matching)
+			}
+		case t.rerolls(face):
+			// Kept only if the one reroll shows it again
+			dist[face] = float64(matching) * p * p
+		default:
+			dist[face] = p + float64(matching)*p*p
+		}
+	}
+	return dist
+}
+
+// Roll rolls every die in the expression
+func (e *DiceExpr) Roll() DiceRoll {
+	roll := DiceRoll{Expr: e, Dice: make([][]Die, len(e.Terms))}
+	for i, term := range e.Terms {
+		if term.Sides == 0 {
+			roll.Total += term.Sign * term.Value
+			continue
+		}
+
+		dice := make([]Die, term.Count)
+		for j := range dice {
+			dice[j] = e.rollDie(term)
+		}
+		dropDice(term, dice)
+		for _, die := range dice {
+			if !die.Dropped {
+				roll.Total += term.Sign * die.Value()
+			}
+		}
+		roll.Dice[i] = dice
+	}
+	return roll
+}
+
+// dropDice marks the dice a keep or drop rule leaves out. Among equal
+// values the later dice are dropped first.
+func dropDice(term DiceTerm, dice []Die) {
+	keep, high := term.keep()
+	order := make([]int, len(dice))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		if high {
+			return dice[order[a]].Value() > dice[order[b]].Value()
+		}
+		return dice[order[a]].Value() < dice[order[b]].Value()
+	})
+	for _, i := range order[keep:] {
+		dice[i].Dropped = true
+	}
+}
+
+// rollDie rolls one die of a term, exploding up to the expression's cap
+func (e *DiceExpr) rollDie(term DiceTerm) Die {
+	if term.Fudge {
+		return Die{Faces: []int{rng.Intn(3) - 1}}
+	}
+	if term.Percentile {
+		tens, ones := rng.Intn(10)*10, rng.Intn(10)
+		if tens == 0 && ones == 0 {
+			tens = 100
+		}
+		return Die{Faces: []int{tens, ones}}
+	}
+	if term.Digits > 0 {
+		value := 0
+		for d := 0; d < term.Digits; d++ {
+			value = value*10 + rng.Intn(term.Sides) + 1
+		}
+		return Die{Faces: []int{value}}
+	}
+	die := Die{Faces: []int{rng.Intn(term.Sides) + 1}}
+	for term.rerolls(die.Faces[0]) && (term.RerollAll || len(die.Rerolled) == 0) {
+		die.Rerolled = append(die.Rerolled, die.Faces[0])
+		die.Faces[0] = rng.Intn(term.Sides) + 1
+	}
+	for term.Explode && die.Faces[len(die.Faces)-1] == term.Sides && len(die.Faces) <= e.ExplodeCap {
+		die.Faces = append(die.Faces, rng.Intn(term.Sides)+1)
+	}
+	return die
+}
+
+// Values returns the value of every die that counts, in expression order
+func (r DiceRoll) Values() []int {
+	return r.values(false)
+}
+
+// DroppedValues returns the value of every die a keep or drop rule left out
+func (r DiceRoll) DroppedValues() []int {
+	return r.values(true)
+}
+
+// RerolledValues returns the faces that rerolls replaced, in order
+func (r DiceRoll) RerolledValues() []int {
+	var values []int
+	for _, dice := range r.Dice {
+		for _, die := range dice {
+			values = append(values, die.Rerolled...)
+		}
+	}
+	return values
+}
+
+func (r DiceRoll) values(dropped bool) []int {
+	var values []int
+	for _, dice := range r.Dice {
+		for _, die := range dice {
+			if die.Dropped == dropped {
+				values = append(values, die.Value())
+			}
+		}
+	}
+	return values
+}
+
+// Detail shows the individual dice behind the total, e.g. "[3, 5, 1] + 2".
+// Faces that exploded are marked with !, followed by the extra roll, and
+// dropped dice are listed separately after the kept ones. Fudge dice show
+// as +, - or a blank, e.g. "[+][ ][-][+]", and percentile dice as their
+// tens and ones, e.g. "[70 + 3 = 73]".
+func (r DiceRoll) Detail() string {
+	var b strings.Builder
+	for i, term := range r.Expr.Terms {
+		switch {
+		case term.Sign < 0 && i == 0:
+			b.WriteString("-")
+		case term.Sign < 0:
+			b.WriteString(" - ")
+		case i > 0:
+			b.WriteString(" + ")
+		}
+
+		if term.Sides == 0 {
+			b.WriteString(strconv.Itoa(term.Value))
+			continue
+		}
+		if term.Fudge {
+			var kept, dropped string
+			for _, die := range r.Dice[i] {
+				symbol := "[" + fudgeSymbol(die.Faces[0]) + "]"
+				if die.Dropped {
+					dropped += symbol
+				} else {
+					kept += symbol
+				}
+			}
+			b.WriteString(kept)
+			if dropped != "" {
+				b.WriteString(" (dropped " + dropped + ")")
+			}
+			continue
+		}
+
+		var kept, dropped []string
+		for _, die := range r.Dice[i] {
+			if term.Percentile {
+				tens := fmt.Sprintf("%02d", die.Faces[0]%100)
+				f := fmt.Sprintf("%s + %d = %d", tens, die.Faces[1], die.Value())
+				if die.Dropped {
+					dropped = append(dropped, f)
+				} else {
+					kept = append(kept, f)
+				}
+				continue
+			}
+			var faces []string
+			for k, face := range die.Faces {
+				f := strconv.Itoa(face)
+				if k == 0 && len(die.Rerolled) > 0 {
+					f += " (rerolled " + strings.Trim(fmt.Sprint(die.Rerolled), "[]") + ")"
+				}
+				if k < len(die.Faces)-1 {
+					f += "!"
+				}
+				faces = append(faces, f)
+			}
+			if die.Dropped {
+				dropped = append(dropped, faces...)
+			} else {
+				kept = append(kept, faces...)
+			}
+		}
+		b.WriteString("[" + strings.Join(kept, ", ") + "]")
+		if len(dropped) > 0 {
+			b.WriteString(" (dropped " + strings.Join(dropped, ", ") + ")")
+		}
+	}
+	return b.String()
+}
+
+// fudgeSymbol renders a fudge die face as +, - or a blank
+func fudgeSymbol(face int) string {
+	switch face {
+	case 1:
+		return "+"
+	case -1:
+		return "-"
+	}
+	return " "
+}
+
+// Distribution works out the exact distribution of the total by convolving
+// the distribution of every die
+func (e *DiceExpr) Distribution() (Distribution, error) {
+	work := 0.0
+	for _, term := range e.Terms {
+		_, hi := term.bounds(e.ExplodeCap)
+		if term.Select != "" {
+			// Faces x dice placed x dice placed x kept sums
+			_, top := DiceTerm{Count: 1, Sides: term.Sides, Digits: term.Digits, Explode: term.Explode}.bounds(e.ExplodeCap)
+			work += float64(top) * float64(term.Count) * float64(term.Count) * float64(hi)
+		} else {
+			work += float64(hi) * float64(hi) / 2
+		}
+	}
+	if work > maxConvolution {
+		return nil, fmt.Errorf("%s has too many outcomes to work out exactly", e)
+	}
+
+	dist := Distribution{0: 1}
+	for _, term := range e.Terms {
+		if term.Sides == 0 {
+			dist = convolve(dist, Distribution{term.Sign * term.Value: 1})
+			continue
+		}
+
+		die := e.dieDistribution(term)
+		if term.Select != "" {
+			keep, high := term.keep()
+			dist = convolve(dist, keepDistribution(die, term.Sign, term.Count, keep, high))
+			continue
+		}
+		for i := 0; i < term.Count; i++ {
+			dist = convolve(dist, die)
+		}
+	}
+	return dist, nil
+}
+
+// dieDistribution returns the distribution of one die of a term, signed.
+// A first face at the top of an exploding die adds an exploding chain with
+// one less explosion to go.
+func (e *DiceExpr) dieDistribution(term DiceTerm) Distribution {
+	die := Distribution{}
+	for face, p := range term.faceDistribution() {
+		if !term.Explode || face != term.Sides || e.ExplodeCap == 0 {
+			die[term.Sign*face] += p
+			continue
+		}
+		for extra, q := range explodingDie(term.Sides, e.ExplodeCap-1) {
+			die[term.Sign*(face+extra)] += p * q
+		}
+	}
+	return die
+}
+
+// explodingDie returns the distribution of a plain exploding die with at
+// most explosions extra rolls. Showing the top face k times and then r is
+// worth k*sides + r; once the cap is reached the last roll stands.
+func explodingDie(sides, explosions int) Distribution {
+	die := Distribution{}
+	p := 1 / float64(sides)
+	for k := 0; k <= explosions; k++ {
+		top := sides - 1
+		if k == explosions {
+			top = sides
+		}
+		for r := 1; r <= top; r++ {
+			die[k*sides+r] += p
+		}
+		p /= float64(sides)
+	}
+	return die
+}
+
+// keepDistribution returns the distribution of the sum of the keep highest
+// (or lowest) of n dice with the given distribution. Values are visited
+// from the kept end, counting how many dice show each one: the first keep
+// dice placed are the ones that count. A negative sign reverses the order of
+// values relative to the faces the dice show.
+func keepDistribution(die Distribution, sign, n, keep int, high bool) Distribution {
+	values := die.Values()
+	if high == (sign > 0) {
+		for i, j := 0, len(values)-1; i < j; i, j = i+1, j-1 {
+			values[i], values[j] = values[j], values[i]
+		}
+	}
+
+	type state struct{ placed, sum int }
+	states := map[state]float64{{0, 0}: 1}
+	for _, v := range values {
+		p := die[v]
+		next := map[state]float64{}
+		for st, w := range states {
+			pc := 1.0
+			for c := 0; st.placed+c <= n; c++ {
+				counted := min(c, max(keep-st.placed, 0))
+				next[state{st.placed + c, st.sum + counted*v}] += w * binomial(n-st.placed, c) * pc
+				pc *= p
+			}
+		}
+		states = next
+	}
+
+	dist := Distribution{}
+	for st, w := range states {
+		if st.placed == n {
+			dist[st.sum] += w
+		}
+	}
+	return dist
+}
+
+// binomial returns n choose k as a float
+func binomial(n, k int) float64 {
+	result := 1.0
+	for i := 1; i <= k; i++ {
+		result = result * float64(n-k+i) / float64(i)
+	}
+	return result
+}
+
+// convolve returns the distribution of the sum of two independent results
+func convolve(a, b Distribution) Distribution {
+	sum := Distribution{}
+	for x, p := range a {
+		for y, q := range b {
+			sum[x+y] += p * q
+		}
+	}
+	return sum
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// exampleFiles holds the example configs and tables shipped with roll
+//
+//go:embed examples
+var exampleFiles embed.FS
+
+// Example is one shipped example and where it installs to
+type Example struct {
+	Kind string
+	Name string
+	File string
+}
+
+var examplesCmd = &cobra.Command{
+	Use:   "examples",
+	Short: "List example configurations and tables to start from",
+	Long: `roll ships example configurations and tables showing off its features:
+pity, time grace, checks, degrees, success expressions and custom tables.
+Install them to try them out or edit them into your own:
+
+  roll examples install
+  roll examples install gacha weather
+  roll odds gacha
+
+Examples are tagged "example", so roll delete --tag example removes the
+configurations again.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if !quiet && !a11y {
+			fmt.Fprintln(w, "TYPE\tNAME\tINSTALLED")
+		}
+		for _, example := range examples() {
+			installed := "no"
+			if _, err := os.Stat(example.Path()); err == nil {
+				installed = "yes"
+			}
+			switch {
+			case quiet:
+				fmt.Println(example.Name)
+			case a11y:
+				fmt.Printf("%s %s, installed: %s.\n", strings.ToUpper(example.Kind[:1])+example.Kind[1:], example.Name, installed)
+			default:
+				fmt.Fprintf(w, "%s\t%s\t%s\n", example.Kind, example.Name, installed)
+			}
+		}
+		w.Flush()
+	},
+}
+
+var examplesInstallCmd = &cobra.Command{
+	Use:   "install [name...]",
+	Short: "Copy examples into the roll directory (all of them by default)",
+	Run: func(cmd *cobra.Command, args []string) {
+		force, _ := cmd.Flags().GetBool("force")
+
+		all := examples()
+		chosen := all
+		if len(args) > 0 {
+			chosen = nil
+			for _, name := range args {
+				found := false
+				for _, example := range all {
+					if example.Name == name {
+						chosen = append(chosen, example)
+						found = true
+					}
+				}
+				if !found {
+					log.Fatalf("No example named '%s'; see roll examples", name)
+				}
+			}
+		}
+
+		for _, example := range chosen {
+			if _, err := os.Stat(example.Path()); err == nil && !force {
+				fmt.Printf("Skipped %s '%s': it already exists (use --force to replace it)\n", example.Kind, example.Name)
+				continue
+			}
+			if err := example.Install(); err != nil {
+				log.Fatalf("Failed to install %s '%s': %v", example.Kind, example.Name, err)
+			}
+			fmt.Printf("Installed %s '%s' to %s\n", example.Kind, example.Name, example.Path())
+		}
+	},
+}
+
+func init() {
+	examplesCmd.AddCommand(examplesInstallCmd)
+	examplesInstallCmd.Flags().Bool("force", false, "Replace configurations and tables that already exist")
+	examplesInstallCmd.ValidArgsFunction = completeEach(exampleNames)
+}
+
+// examples lists the shipped examples, configs first
+func examples() []Example {
+	var list []Example
+	for _, dir := range []struct{ kind, name string }{{"config", "configs"}, {"table", "tables"}} {
+		files, err := fs.ReadDir(exampleFiles, path.Join("examples", dir.name))
+		if err != nil {
+			log.Fatal("Failed to read examples:", err)
+		}
+		for _, file := range files {
+			name := strings.TrimSuffix(file.Name(), path.Ext(file.Name()))
+			list = append(list, Example{dir.kind, name, path.Join("examples", dir.name, file.Name())})
+		}
+	}
+	return list
+}
+
+func exampleNames() []string {
+	var names []string
+	for _, example := range examples() {
+		names = append(names, example.Name)
+	}
+	return names
+}
+
+// Path is where the example installs to
+func (e Example) Path() string {
+	if e.Kind == "table" {
+		return tablePath(e.Name)
+	}
+	return filepath.Join(configDir, e.Name+".toml")
+}
+
+// Install copies the example into place. Configs are checked like an edited
+// config and start with fresh state, as if made with create.
+func (e Example) Install() error {
+	data, err := exampleFiles.ReadFile(e.File)
+	if err != nil {
+		return err
+	}
+
+	if e.Kind == "config" {
+		if errs := checkConfigData(e.Name, data); len(errs) > 0 {
+			return errs[0]
+		}
+	} else if lines, err := scanLines(strings.NewReader(string(data))); err != nil || len(lines) == 0 {
+		return fmt.Errorf("table has no entries")
+	}
+
+	if err := os.MkdirAll(filepath.Dir(e.Path()), 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(e.Path(), data, 0644); err != nil {
+		return err
+	}
+	if e.Kind == "config" {
+		if err := initState(e.Name); err != nil {
+			return err
+		}
+		return snapshotConfig(e.Name)
+	}
+	return nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"io/fs"
+	"path"
+	"strings"
+	"testing"
+)
+
+// TestExamples checks every shipped example the way examples install does,
+// so a broken example fails here rather than on a user's machine
+func TestExamples(t *testing.T) {
+	found := map[string]int{}
+	err := fs.WalkDir(exampleFiles, "examples", func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+		data, err := exampleFiles.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		dir, ext := path.Dir(file), path.Ext(file)
+		name := strings.TrimSuffix(path.Base(file), ext)
+		switch {
+		case dir == "examples/configs" && ext == ".toml":
+			for _, err := range checkConfigData(name, data) {
+				t.Errorf("config %s: %v", file, err)
+			}
+		case dir == "examples/tables" && ext == ".txt":
+			entries, err := scanLines(bytes.NewReader(data))
+			if err != nil {
+				t.Errorf("table %s: %v", file, err)
+			} else if len(entries) == 0 {
+				t.Errorf("table %s has no entries", file)
+			}
+		default:
+			t.Errorf("unexpected file %s: examples install only picks up configs/*.toml and tables/*.txt", file)
+		}
+		found[dir]++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, dir := range []string{"examples/configs", "examples/tables"} {
+		if found[dir] == 0 {
+			t.Errorf("no examples in %s", dir)
+		}
+	}
+	if got := len(examples()); got != found["examples/configs"]+found["examples/tables"] {
+		t.Errorf("examples lists %d examples, want one per file", got)
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Expr is a compiled success predicate such as "roll <= chance || roll == 100".
+// It supports integer arithmetic (+ - * / %), comparisons, && || ! and
+// parentheses over a fixed set of variables, and nothing else.
+type Expr struct {
+	src  string
+	root exprNode
+}
+
+// exprValue is either an integer or a boolean
+type exprValue struct {
+	n      int
+	b      bool
+	isBool bool
+}
+
+type exprNode interface {
+	eval(vars map[string]int) (exprValue, error)
+}
+
+type exprNum int
+
+type exprVar string
+
+type exprUnary struct {
+	op string
+	x  exprNode
+}
+
+type exprBinary struct {
+	op   string
+	x, y exprNode
+}
+
+// compileExpr parses an expression, checking that only the given variables
+// are used
+func compileExpr(src string, vars []string) (*Expr, error) {
+	tokens, err := tokenizeExpr(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{tokens: tokens, vars: vars}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %q", p.tokens[p.pos])
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// EvalBool evaluates the expression, which must produce a boolean
+func (e *Expr) EvalBool(vars map[string]int) (bool, error) {
+	v, err := e.root.eval(vars)
+	if err != nil {
+		return false, err
+	}
+	if !v.isBool {
+		return false, fmt.Errorf("%q does not produce true or false", e.src)
+	}
+	return v.b, nil
+}
+
+func tokenizeExpr(src string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(src); {
+		c := rune(src[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c):
+			j := i
+			for j < len(src) && unicode.IsDigit(rune(src[j])) {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(src) && (unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j])) || src[j] == '_') {
+				j++
+			}
+			tokens = append(tokens, src[i:j])
+			i = j
+		default:
+			if i+1 < len(src) {
+				switch two := src[i : i+2]; two {
+				case "<=", ">=", "==", "!=", "&&", "||":
+					tokens = append(tokens, two)
+					i += 2
+					continue
+				}
+			}
+			if !strings.ContainsRune("+-*/%<>!()", c) {
+				return nil, fmt.Errorf("unexpected character %q", c)
+			}
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return tokens, nil
+}
+
+type exprParser struct {
+	tokens []string
+	pos    int
+	vars   []string
+}
+
+func (p *exprParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *exprParser) next() string {
+	t := p.peek()
+	p.pos++
+	return t
+}
+
+// binary parses a left-associative level of binary operators
+func (p *exprParser) binary(ops []string, operand func() (exprNode, error)) (exprNode, error) {
+	x, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := p.peek()
+		found := false
+		for _, o := range ops {
+			if op == o {
+				found = true
+			}
+		}
+		if !found {
+			return x, nil
+		}
+		p.next()
+		y, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		x = exprBinary{op: op, x: x, y: y}
+	}
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	return p.binary([]string{"||"}, p.parseAnd)
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	return p.binary([]string{"&&"}, p.parseComparison)
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	return p.binary([]string{"<", "<=", ">", ">=", "==", "!="}, p.parseAdditive)
+}
+
+func (p *exprParser) parseAdditive() (exprNode, error) {
+	return p.binary([]string{"+", "-"}, p.parseMultiplicative)
+}
+
+func (p *exprParser) parseMultiplicative() (exprNode, error) {
+	return p.binary([]string{"*", "/", "%"}, p.parseUnary)
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if op := p.peek(); op == "!" || op == "-" {
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return exprUnary{op: op, x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	t := p.next()
+	switch {
+	case t == "":
+		return nil, fmt.Errorf("unexpected end of expression")
+	case t == "(":
+		x, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return x, nil
+	case unicode.IsDigit(rune(t[0])):
+		n, err := strconv.Atoi(t)
+		if err != nil {
+			return nil, err
+		}
+		return exprNum(n), nil
+	case unicode.IsLetter(rune(t[0])) || t[0] == '_':
+		for _, v := range p.vars {
+			if t == v {
+				return exprVar(t), nil
+			}
+		}
+		return nil, fmt.Errorf("unknown variable %q (available: %s)", t, strings.Join(p.vars, ", "))
+	}
+	return nil, fmt.Errorf("unexpected %q", t)
+}
+
+func (n exprNum) eval(vars map[string]int) (exprValue, error) {
+	return exprValue{n: int(n)}, nil
+}
+
+func (v exprVar) eval(vars map[string]int) (exprValue, error) {
+	return exprValue{n: vars[string(v)]}, nil
+}
+
+func (u exprUnary) eval(vars map[string]int) (exprValue, error) {
+	x, err := u.x.eval(vars)
+	if err != nil {
+		return x, err
+	}
+	if u.op == "!" {
+		if !x.isBool {
+			return x, fmt.Errorf("! needs true or false")
+		}
+		return exprValue{b: !x.b, isBool: true}, nil
+	}
+	if x.isBool {
+		return x, fmt.Errorf("- needs a number")
+	}
+	return exprValue{n: -x.n}, nil
+}
+
+func (b exprBinary) eval(vars map[string]int) (exprValue, error) {
+	x, err := b.x.eval(vars)
+	if err != nil {
+		return x, err
+	}
+
+	// Short-circuit logical operators
+	if b.op == "&&" || b.op == "||" {
+		if !x.isBool {
+			return x, fmt.Errorf("%s needs true or false", b.op)
+		}
+		if (b.op == "&&" && !x.b) || (b.op == "||" && x.b) {
+			return x, nil
+		}
+		y, err := b.y.eval(vars)
+		if err != nil {
+			return y, err
+		}
+		if !y.isBool {
+			return y, fmt.Errorf("%s needs true or false", b.op)
+		}
+		return y, nil
+	}
+
+	y, err := b.y.eval(vars)
+	if err != nil {
+		return y, err
+	}
+
+	if b.op == "==" || b.op == "!=" {
+		if x.isBool != y.isBool {
+			return x, fmt.Errorf("cannot compare a number with true or false")
+		}
+		equal := x.n == y.n && x.b == y.b
+		return exprValue{b: equal == (b.op == "=="), isBool: true}, nil
+	}
+
+	if x.isBool || y.isBool {
+		return x, fmt.Errorf("%s needs numbers", b.op)
+	}
+
+	switch b.op {
+	case "+":
+		return exprValue{n: x.n + y.n}, nil
+	case "-":
+		return exprValue{n: x.n - y.n}, nil
+	case "*":
+		return exprValue{n: x.n * y.n}, nil
+	case "/", "%":
+		if y.n == 0 {
+			return x, fmt.Errorf("division by zero")
+		}
+		if b.op == "/" {
+			return exprValue{n: x.n / y.n}, nil
+		}
+		return exprValue{n: x.n % y.n}, nil
+	case "<":
+		return exprValue{b: x.n < y.n, isBool: true}, nil
+	case "<=":
+		return exprValue{b: x.n <= y.n, isBool: true}, nil
+	case ">":
+		return exprValue{b: x.n > y.n, isBool: true}, nil
+	case ">=":
+		return exprValue{b: x.n >= y.n, isBool: true}, nil
+	}
+	return x, fmt.Errorf("unknown operator %s", b.op)
+}
+
+// predicateVars are the variables a config's success expression may use
+var predicateVars = []string{"roll", "chance", "base", "pity", "grace"}
+
+// successExprs holds compiled success expressions by source, since odds and
+// validation evaluate the same expression many thousands of times
+var successExprs = map[string]*Expr{}
+
+// successExpr compiles a success expression, reusing an earlier compile
+func successExpr(src string) (*Expr, error) {
+	if expr, ok := successExprs[src]; ok {
+		return expr, nil
+	}
+	expr, err := compileExpr(src, predicateVars)
+	if err != nil {
+		return nil, err
+	}
+	successExprs[src] = expr
+	return expr, nil
+}
+
+// rollSucceeds decides whether a roll succeeds, using the config's success
+// expression if it has one and roll <= chance otherwise
+func rollSucceeds(config *Config, roll, chance, pity int) (bool, error) {
+	if config.Success == "" {
+		return roll <= chance, nil
+	}
+
+	expr, err := successExpr(config.Success)
+	if err != nil {
+		return false, err
+	}
+	return expr.EvalBool(map[string]int{
+		"roll":   roll,
+		"chance": chance,
+		"base":   config.Chance,
+		"pity":   pity,
+		"grace":  config.Grace,
+	})
+}
+
+// validateSuccess checks that a success expression compiles and yields true
+// or false wherever it is evaluated. Every roll is tried against every chance
+// at pity 0 and against the chances each pity level reaches until the chance
+// caps. Beyond that only pity changes, so the first and last roll are enough
+// to catch expressions that break at a given pity.
+func validateSuccess(config *Config) []error {
+	if config.Success == "" {
+		return nil
+	}
+	if len(config.Checks) > 0 {
+		return []error{fmt.Errorf("Success cannot be used with checks")}
+	}
+
+	expr, err := successExpr(config.Success)
+	if err != nil {
+		return []error{fmt.Errorf("Success expression: %v", err)}
+	}
+	env := map[string]int{"base": config.Chance, "grace": config.Grace}
+	check := func(roll, chance, pity int) error {
+		env["roll"], env["chance"], env["pity"] = roll, chance, pity
+		if _, err := expr.EvalBool(env); err != nil {
+			return fmt.Errorf("Success expression at pity %d, chance %d, roll %d: %v", pity, chance, roll, err)
+		}
+		return nil
+	}
+
+	for chance := 0; chance <= 100; chance++ {
+		for roll := 1; roll <= 100; roll++ {
+			if err := check(roll, chance, 0); err != nil {
+				return []error{err}
+			}
+		}
+	}
+
+	capped := false
+	for pity := 1; pity <= config.Pity; pity++ {
+		// Variance can add one more grace to the chance
+		chance := min(config.Chance+pity*config.Grace, 100)
+		chances := []int{chance, min(chance+config.Grace, 100)}
+		rolls := []int{1, 100}
+		if !capped {
+			rolls = rolls[:0]
+			for roll := 1; roll <= 100; roll++ {
+				rolls = append(rolls, roll)
+			}
+		}
+		for _, chance := range chances {
+			for _, roll := range rolls {
+				if err := check(roll, chance, pity); err != nil {
+					return []error{err}
+				}
+			}
+		}
+		capped = chance == 100 || config.Grace <= 0
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// separators for formatting numbers, set from the locale setting
+var (
+	decimalSep = "."
+	groupSep   = ","
+)
+
+// localeSeparators maps language codes to their decimal and group separators.
+// Languages not listed use the English style.
+var localeSeparators = map[string][2]string{
+	"de":    {",", "."},
+	"es":    {",", "."},
+	"it":    {",", "."},
+	"nl":    {",", "."},
+	"pt":    {",", "."},
+	"da":    {",", "."},
+	"id":    {",", "."},
+	"tr":    {",", "."},
+	"fr":    {",", " "},
+	"ru":    {",", " "},
+	"pl":    {",", " "},
+	"sv":    {",", " "},
+	"nb":    {",", " "},
+	"fi":    {",", " "},
+	"cs":    {",", " "},
+	"uk":    {",", " "},
+	"de_CH": {".", "'"},
+}
+
+// setLocale picks number separators for a locale such as "de", "de-DE" or
+// "de_DE.UTF-8". An empty locale falls back to the environment.
+func setLocale(locale string) {
+	if locale == "" {
+		for _, env := range []string{"LC_ALL", "LC_NUMERIC", "LANG"} {
+			if locale = os.Getenv(env); locale != "" {
+				break
+			}
+		}
+	}
+
+	locale, _, _ = strings.Cut(strings.ReplaceAll(locale, "-", "_"), ".")
+	lang, _, _ := strings.Cut(locale, "_")
+
+	seps, ok := localeSeparators[locale]
+	if !ok {
+		seps, ok = localeSeparators[lang]
+	}
+	if !ok {
+		seps = [2]string{".", ","}
+	}
+	decimalSep, groupSep = seps[0], seps[1]
+}
+
+// formatFloat formats f with the given number of decimals and the locale's
+// separators
+func formatFloat(f float64, decimals int) string {
+	s := strconv.FormatFloat(f, 'f', decimals, 64)
+	whole, frac, _ := strings.Cut(s, ".")
+
+	neg := strings.HasPrefix(whole, "-")
+	whole = strings.TrimPrefix(whole, "-")
+
+	var b strings.Builder
+	if neg {
+		b.WriteString("-")
+	}
+	for i, c := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			b.WriteString(groupSep)
+		}
+		b.WriteRune(c)
+	}
+	if frac != "" {
+		b.WriteString(decimalSep)
+		b.WriteString(frac)
+	}
+	return b.String()
+}
+
+// formatPercent formats a probability as a percentage using the precision
+// setting
+func formatPercent(p float64) string {
+	return formatFloat(p*100, settings.Precision) + "%"
+}
+
+// formatCount formats a whole number with group separators
+func formatCount(n int) string {
+	return formatFloat(float64(n), 0)
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// GateDecision is a remembered gate result for a sticky key
+type GateDecision struct {
+	Selected  bool      `json:"selected"`
+	Percent   float64   `json:"percent"`
+	DecidedAt time.Time `json:"decided_at"`
+}
+
+// GateReport is the JSON printed for a gate under --ci
+type GateReport struct {
+	Key      string  `json:"key,omitempty"`
+	Selected bool    `json:"selected"`
+	Percent  float64 `json:"percent"`
+	Method   string  `json:"method"`
+}
+
+var gateCmd = &cobra.Command{
+	Use:   "gate",
+	Short: "Decide whether this host or run is selected, for rollouts and chaos experiments",
+	Long: `Select a percentage of hosts or runs:
+
+  roll gate --percent 5 --key "$HOSTNAME"
+
+With --key the decision comes from a hash of the key, so the same key is
+always selected or always skipped at the same percentage, and raising the
+percentage only adds keys. --salt gives each experiment its own selection.
+Without --key every run is decided at random.
+
+--sticky remembers a key's first decision, made at random, and repeats it
+on later runs until it is forgotten with --forget.
+
+The exit code is 0 when selected and 2 when skipped under --ci.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		percent, _ := cmd.Flags().GetFloat64("percent")
+		key, _ := cmd.Flags().GetString("key")
+		salt, _ := cmd.Flags().GetString("salt")
+		sticky, _ := cmd.Flags().GetBool("sticky")
+		forget, _ := cmd.Flags().GetBool("forget")
+
+		if percent < 0 || percent > 100 {
+			log.Fatal("Percent must be between 0 and 100")
+		}
+		if (sticky || forget) && key == "" {
+			log.Fatal("--sticky and --forget need a --key to remember the decision by")
+		}
+		stickyKey := []byte(salt + "/" + key)
+
+		if forget {
+			err := db.Update(func(tx *bolt.Tx) error {
+				if b := tx.Bucket([]byte("gates")); b != nil {
+					return b.Delete(stickyKey)
+				}
+				return nil
+			})
+			if err != nil {
+				log.Fatal("Failed to forget gate:", err)
+			}
+			if !quiet {
+				fmt.Printf("Forgot the decision for '%s'\n", key)
+			}
+			return
+		}
+
+		var selected bool
+		var method string
+		var decision *GateDecision
+		switch {
+		case sticky:
+			var err error
+			decision, err = stickyGate(stickyKey, percent)
+			if err != nil {
+				log.Fatal("Failed to update gate:", err)
+			}
+			selected, method = decision.Selected, "sticky"
+		case key != "":
+			selected, method = hashFraction(salt, key) < percent/100, "hash"
+		default:
+			selected, method = rng.Float64() < percent/100, "random"
+		}
+
+		if verbose {
+			if method == "hash" {
+				fmt.Printf("Hash of '%s': %s, selected below %s\n", key,
+					formatFloat(hashFraction(salt, key), 6), formatFloat(percent/100, 6))
+			} else {
+				printProvenance()
+			}
+		}
+
+		switch {
+		case ci:
+			data, err := json.Marshal(GateReport{Key: key, Selected: selected, Percent: percent, Method: method})
+			if err != nil {
+				log.Fatal("Failed to print result:", err)
+			}
+			fmt.Println(string(data))
+		case quiet:
+			if selected {
+				fmt.Println("selected")
+			} else {
+				fmt.Println("skipped")
+			}
+		default:
+			who := "This run"
+			if key != "" {
+				who = "'" + key + "'"
+			}
+			outcome := "skipped"
+			if selected {
+				outcome = "selected"
+			}
+			if !a11y {
+				if selected {
+					fmt.Print("✅ ")
+				} else {
+					fmt.Print("❌ ")
+				}
+			}
+			fmt.Printf("%s is %s at %s (%s)\n", who, outcome, formatPercent(percent/100), method)
+			if decision != nil && decision.Percent != percent {
+				fmt.Printf("Decided on %s at %s; use --forget to decide again\n",
+					formatTime(decision.DecidedAt), formatPercent(decision.Percent/100))
+			}
+		}
+
+		if ci && !selected {
+			os.Exit(exitRollFailed)
+		}
+	},
+}
+
+func init() {
+	gateCmd.Flags().Float64("percent", 0, "Percentage of keys or runs to select")
+	gateCmd.Flags().String("key", "", "Key to decide by, e.g. a hostname; random per run if empty")
+	gateCmd.Flags().String("salt", "", "Mixed into the hash so each experiment selects different keys")
+	gateCmd.Flags().Bool("sticky", false, "Remember the key's first decision and repeat it")
+	gateCmd.Flags().Bool("forget", false, "Forget the key's remembered decision")
+}
+
+// hashFraction maps a salt and key to a stable number in [0, 1)
+func hashFraction(salt, key string) float64 {
+	sum := sha256.Sum256([]byte(salt + "\x00" + key))
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11) / (1 << 53)
+}
+
+// stickyGate returns the remembered decision for a key, deciding at random
+// and storing it the first time
+func stickyGate(key []byte, percent float64) (*GateDecision, error) {
+	var decision GateDecision
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("gates"))
+		if err != nil {
+			return err
+		}
+		if data := b.Get(key); data != nil {
+			return json.Unmarshal(data, &decision)
+		}
+
+		decision = GateDecision{
+			Selected:  rng.Float64() < percent/100,
+			Percent:   percent,
+			DecidedAt: time.Now().UTC(),
+		}
+		data, err := json.Marshal(decision)
+		if err != nil {
+			return err
+		}
+		return b.Put(key, data)
+	})
+	return &decision, err
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// graceInterval parses a config's grace_per setting. It returns 0 when grace
+// accrues per failed roll, which is the default.
+func graceInterval(config *Config) (time.Duration, error) {
+	switch config.GracePer {
+	case "", "roll":
+		return 0, nil
+	case "hour":
+		return time.Hour, nil
+	case "day":
+		return 24 * time.Hour, nil
+	case "week":
+		return 7 * 24 * time.Hour, nil
+	}
+
+	d, err := time.ParseDuration(config.GracePer)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("Grace per must be roll, hour, day, week or a duration like 12h")
+	}
+	return d, nil
+}
+
+// pityLevel returns how many grace steps a config has earned: failed rolls
+// for per-roll grace, or whole intervals since the last success for time
+// grace. Either way it is capped at the config's pity.
+func pityLevel(config *Config, state *State, now time.Time) int {
+	interval, _ := graceInterval(config)
+	if interval == 0 {
+		return state.PityCounter
+	}
+	if state.LastSuccessAt.IsZero() || now.Before(state.LastSuccessAt) {
+		return 0
+	}
+	return min(int(now.Sub(state.LastSuccessAt)/interval), config.Pity)
+}
+
+// rollChain returns the config to feed the odds engine from the given level.
+// The engine assumes every failure moves one pity level up; with time grace
+// rolling does not move the chance, so the chain is pinned at that level.
+func rollChain(config *Config, level int) *Config {
+	if interval, _ := graceInterval(config); interval == 0 {
+		return config
+	}
+
+	pinned := *config
+	pinned.Pity = level
+	return &pinned
+}
+
+// graceUnit describes what grace accrues per, for display
+func graceUnit(config *Config) string {
+	if interval, _ := graceInterval(config); interval == 0 {
+		return "fail"
+	}
+	return config.GracePer
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// GroupState tracks which config in a mutual exclusion group has succeeded
+type GroupState struct {
+	LockedBy string    `json:"locked_by"`
+	LockedAt time.Time `json:"locked_at"`
+}
+
+var groupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage mutual exclusion groups of configurations",
+	Long: `Only one configuration in a group may succeed per period. Once one
+succeeds the group is locked, and the others cannot be rolled until the
+period ends or the group is reset.
+
+The period is set by group_period in settings.toml: day, week (starting
+Monday) or month, in the display timezone. Without it a lock lasts until
+roll group reset.`,
+}
+
+var groupStatusCmd = &cobra.Command{
+	Use:   "status [group]",
+	Short: "Show which groups are locked and by which configuration",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		members := groupMembers()
+		if len(args) == 1 {
+			if _, ok := members[args[0]]; !ok {
+				log.Fatalf("No configurations in group '%s'", args[0])
+			}
+			members = map[string][]string{args[0]: members[args[0]]}
+		}
+		if len(members) == 0 {
+			fmt.Println("No groups defined")
+			return
+		}
+
+		groups := make([]string, 0, len(members))
+		for group := range members {
+			groups = append(groups, group)
+		}
+		sort.Strings(groups)
+
+		for _, group := range groups {
+			names := members[group]
+			lock, err := loadGroupState(group)
+			if err != nil {
+				log.Fatal("Failed to load group state:", err)
+			}
+
+			fmt.Printf("%s: %s\n", group, strings.Join(names, ", "))
+			_, end := groupPeriod(time.Now())
+			switch {
+			case !lock.Locked(time.Now()):
+				fmt.Printf("  Open\n")
+			case end.IsZero():
+				fmt.Printf("  Locked by '%s' since %s\n", lock.LockedBy, formatTime(lock.LockedAt))
+			default:
+				fmt.Printf("  Locked by '%s' since %s, until %s\n", lock.LockedBy, formatTime(lock.LockedAt), formatTime(end))
+			}
+		}
+	},
+}
+
+var groupResetCmd = &cobra.Command{
+	Use:   "reset [group]",
+	Short: "Unlock a group so any of its configurations can succeed again",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		group := args[0]
+
+		err := db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte("groups"))
+			if b == nil {
+				return nil
+			}
+			return b.Delete([]byte(group))
+		})
+		if err != nil {
+			log.Fatal("Failed to reset group:", err)
+		}
+
+		fmt.Printf("Reset group '%s'\n", group)
+	},
+}
+
+func init() {
+	groupCmd.AddCommand(groupStatusCmd)
+	groupCmd.AddCommand(groupResetCmd)
+	groupStatusCmd.ValidArgsFunction = completeArgs(groupNames)
+	groupResetCmd.ValidArgsFunction = completeArgs(groupNames)
+}
+
+// groupMembers maps each group name to the configs that belong to it
+func groupMembers() map[string][]string {
+	members := map[string][]string{}
+
+	for _, name := range configNames() {
+		config, err := loadConfig(name)
+		if err != nil || config.Group == "" {
+			continue
+		}
+		members[config.Group] = append(members[config.Group], name)
+	}
+	return members
+}
+
+func loadGroupState(group string) (*GroupState, error) {
+	var lock GroupState
+	err := db.View(func(tx *bolt.Tx) error {
+		var err error
+		lock, err = getGroupState(tx, group)
+		return err
+	})
+	return &lock, err
+}
+
+// getGroupState reads a group's lock within a transaction. Groups that were
+// never locked have no entry.
+func getGroupState(tx *bolt.Tx, group string) (GroupState, error) {
+	var lock GroupState
+	b := tx.Bucket([]byte("groups"))
+	if b == nil {
+		return lock, nil
+	}
+	data := b.Get([]byte(group))
+	if data == nil {
+		return lock, nil
+	}
+	err := json.Unmarshal(data, &lock)
+	return lock, err
+}
+
+// Locked reports whether the lock still holds at now. Locks taken before the
+// current group period have expired.
+func (g GroupState) Locked(now time.Time) bool {
+	if g.LockedBy == "" {
+		return false
+	}
+	start, _ := groupPeriod(now)
+	return !g.LockedAt.Before(start)
+}
+
+// groupPeriod returns when the group period containing now starts and ends,
+// in the display timezone. Both are zero when locks last until group reset.
+func groupPeriod(now time.Time) (time.Time, time.Time) {
+	t := now.In(displayLocation)
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, displayLocation)
+	switch settings.GroupPeriod {
+	case "day":
+		return day, day.AddDate(0, 0, 1)
+	case "week":
+		// Weeks start on Monday
+		start := day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+		return start, start.AddDate(0, 0, 7)
+	case "month":
+		start := day.AddDate(0, 0, 1-day.Day())
+		return start, start.AddDate(0, 1, 0)
+	}
+	return time.Time{}, time.Time{}
+}
+
+// lockGroup records that name succeeded, locking the rest of its group
+func lockGroup(tx *bolt.Tx, group, name string) error {
+	b, err := tx.CreateBucketIfNotExists([]byte("groups"))
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(GroupState{LockedBy: name, LockedAt: time.Now().UTC()})
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(group), data)
+}
+
+// checkGroupLock returns an error if another config in the group already
+// succeeded this period and since the last reset
+func checkGroupLock(tx *bolt.Tx, config *Config) error {
+	if config.Group == "" {
+		return nil
+	}
+
+	lock, err := getGroupState(tx, config.Group)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if !lock.Locked(now) {
+		return nil
+	}
+	if _, end := groupPeriod(now); !end.IsZero() {
+		return fmt.Errorf("group '%s' is locked by '%s' until %s (run roll group reset %s)",
+			config.Group, lock.LockedBy, formatTime(end), config.Group)
+	}
+	return fmt.Errorf("group '%s' is locked by '%s' since %s (run roll group reset %s)",
+		config.Group, lock.LockedBy, formatTime(lock.LockedAt), config.Group)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// maxHistory is how many entries each history bucket keeps
+const maxHistory = 100
+
+// DiceRecord is one dice roll as it was asked for
+type DiceRecord struct {
+	Expr       string    `json:"expr"`
+	Shift      int       `json:"shift,omitempty"`
+	Label      string    `json:"label,omitempty"`
+	Adv        int       `json:"adv,omitempty"`
+	ExplodeCap *int      `json:"explode_cap,omitempty"`
+	Pool       *Pool     `json:"pool,omitempty"`
+	Wild       string    `json:"wild,omitempty"`
+	RolledAt   time.Time `json:"rolled_at"`
+}
+
+var diceHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show recent dice rolls, newest first",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		records, err := loadDiceHistory()
+		if err != nil {
+			log.Fatal("Failed to load dice history:", err)
+		}
+		if len(records) == 0 {
+			if !quiet {
+				fmt.Println("No dice rolls yet")
+			}
+			return
+		}
+
+		for i := len(records) - 1; i >= 0 && (limit <= 0 || len(records)-i <= limit); i-- {
+			record := records[i]
+			if quiet {
+				fmt.Println(record.Command())
+				continue
+			}
+			fmt.Printf("%s  %s\n", formatTime(record.RolledAt), record.Command())
+		}
+	},
+}
+
+func init() {
+	diceCmd.AddCommand(diceHistoryCmd)
+	diceHistoryCmd.Flags().IntP("limit", "n", 10, "Number of rolls to show (0 for all)")
+}
+
+// Command returns the dice arguments that repeat this roll
+func (r DiceRecord) Command() string {
+	command := r.Expr
+	if r.Shift != 0 {
+		command += fmt.Sprintf(" --shift %d", r.Shift)
+	}
+	if r.Adv > 0 {
+		command += " --adv"
+	} else if r.Adv < 0 {
+		command += " --dis"
+	}
+	if r.ExplodeCap != nil {
+		command += fmt.Sprintf(" --explode-cap %d", *r.ExplodeCap)
+	}
+	if r.Pool != nil {
+		command += fmt.Sprintf(" --target %d", r.Pool.Target)
+		if r.Pool.Double > 0 {
+			command += fmt.Sprintf(" --double %d", r.Pool.Double)
+		}
+		if r.Pool.Botch {
+			command += " --botch"
+		}
+		if r.Pool.Glitch {
+			command += " --glitch"
+		}
+	}
+	if r.Wild != "" {
+		command += " --wild " + r.Wild
+	}
+	if r.Label != "" {
+		command += fmt.Sprintf(" --label %q", r.Label)
+	}
+	return command
+}
+
+// recordDice appends a roll to the dice history
+func recordDice(record DiceRecord) error {
+	return appendHistory("dice_history", record)
+}
+
+// appendHistory stores a record as JSON at the end of a history bucket,
+// dropping the oldest entries past maxHistory
+func appendHistory(bucket string, record interface{}) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte(bucket))
+		if err != nil {
+			return err
+		}
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+
+		value, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(key, value); err != nil {
+			return err
+		}
+
+		var keys [][]byte
+		b.ForEach(func(k, v []byte) error {
+			keys = append(keys, append([]byte(nil), k...))
+			return nil
+		})
+		for len(keys) > maxHistory {
+			if err := b.Delete(keys[0]); err != nil {
+				return err
+			}
+			keys = keys[1:]
+		}
+		return nil
+	})
+}
+
+// loadDiceHistory returns the stored dice rolls, oldest first
+func loadDiceHistory() ([]DiceRecord, error) {
+	var records []DiceRecord
+	err := loadHistory("dice_history", func(v []byte) error {
+		var record DiceRecord
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+		records = append(records, record)
+		return nil
+	})
+	return records, err
+}
+
+// loadHistory calls fn with each entry of a history bucket, oldest first
+func loadHistory(bucket string, fn func(v []byte) error) error {
+	return db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucket))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			return fn(v)
+		})
+	})
+}
+
+// lastDice returns the most recent dice roll, or nil if there is none
+func lastDice() (*DiceRecord, error) {
+	var record *DiceRecord
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("dice_history"))
+		if b == nil {
+			return nil
+		}
+		_, v := b.Cursor().Last()
+		if v == nil {
+			return nil
+		}
+		record = &DiceRecord{}
+		return json.Unmarshal(v, record)
+	})
+	return record, err
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var lintCmd = &cobra.Command{
+	Use:   "lint [name]",
+	Short: "Check roll configurations for suspicious setups",
+	Args:  cobra.MaximumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		all, _ := cmd.Flags().GetBool("all")
+
+		var names []string
+		switch {
+		case len(args) == 1 && !all:
+			names = args
+		case len(args) == 0 && all:
+			names = configNames()
+		default:
+			log.Fatal("Specify a configuration name or --all")
+		}
+
+		problems := 0
+		for _, name := range names {
+			config, err := loadConfig(name)
+			if err != nil {
+				fmt.Printf("%s: failed to load: %v\n", name, err)
+				problems++
+				continue
+			}
+
+			findings := lintConfig(config)
+			if len(findings) == 0 {
+				fmt.Printf("%s: ok\n", name)
+				continue
+			}
+			for _, finding := range findings {
+				fmt.Printf("%s: %s\n", name, finding)
+			}
+			problems += len(findings)
+		}
+
+		if problems > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	lintCmd.Flags().Bool("all", false, "Lint every configuration")
+	lintCmd.ValidArgsFunction = completeArgs(configNames)
+}
+
+// minLintChance is the chance at max pity below which lint warns that a
+// config practically never succeeds (one in ten thousand rolls)
+const minLintChance = 0.0001
+
+// lintConfig returns warnings for configs that are valid but probably not
+// what the user meant
+func lintConfig(config *Config) []string {
+	var findings []string
+
+	// Hand-written configs never went through create's validation
+	for _, err := range validateConfig(config) {
+		findings = append(findings, "invalid: "+err.Error())
+	}
+
+	// Expressions that fail to evaluate are already reported as invalid
+	if chance, err := successChance(config, config.Pity); err == nil && chance == 0 {
+		findings = append(findings, "can never succeed: chance stays at 0% even at max pity")
+	} else if err == nil && chance < minLintChance {
+		findings = append(findings, fmt.Sprintf("almost never succeeds: even at max pity a success takes %s rolls on average",
+			formatFloat(1/chance, 0)))
+	}
+	if chance, err := passChance(config, 0, 0); err == nil && chance >= 1 {
+		switch {
+		case len(config.Checks) > 0:
+			findings = append(findings, fmt.Sprintf("always succeeds: the checks always give the %d of %d passes needed, so pity is never used",
+				requiredChecks(config), len(config.Checks)))
+		case config.Success != "":
+			findings = append(findings, "always succeeds: the success expression accepts every roll at pity 0, so pity is never used")
+		default:
+			findings = append(findings, "always succeeds: base chance is 100%, so pity is never used")
+		}
+	}
+
+	if len(config.Checks) == 0 && config.Chance < 100 && config.Grace > 0 {
+		// First pity level where the chance is already capped
+		capped := (100 - config.Chance + config.Grace - 1) / config.Grace
+		if capped < config.Pity {
+			findings = append(findings, fmt.Sprintf(
+				"chance reaches 100%% at pity %d, so pity levels %d-%d are never reached",
+				capped, capped+1, config.Pity))
+		}
+	}
+
+	if config.Grace == 0 && config.Pity > 0 {
+		findings = append(findings, "pity is set but grace is 0, so failures never raise the chance")
+	}
+	if config.Grace == 0 && config.Variance > 0 {
+		findings = append(findings, "variance is set but grace is 0, so variance never changes the chance")
+	}
+	if config.Grace > 0 && config.Pity == 0 && config.Variance == 0 {
+		findings = append(findings, "grace is set but pity and variance are 0, so grace is never applied")
+	}
+
+	return findings
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"unicode"
+
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+var macroCmd = &cobra.Command{
+	Use:   "macro",
+	Short: "List dice macros, named expressions that dice rolls by name",
+	Long: `A macro names a dice expression so it can be rolled by name:
+
+  roll macro add attack d20+7
+  roll macro add sneak 4d6
+  roll dice attack --adv
+
+Any dice flags can be given alongside a macro, and the roll is labelled with
+the macro's name. Deleted macros go to the trash.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		macros, err := loadMacros()
+		if err != nil {
+			log.Fatal("Failed to load macros:", err)
+		}
+		if len(macros) == 0 {
+			if !quiet {
+				fmt.Println("No macros yet. Add one with: roll macro add [name] [expression]")
+			}
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if !quiet && !a11y {
+			fmt.Fprintln(w, "NAME\tEXPRESSION")
+		}
+		for _, name := range macroNames() {
+			switch {
+			case quiet:
+				fmt.Printf("%s\t%s\n", name, macros[name])
+			case a11y:
+				fmt.Printf("%s rolls %s.\n", name, macros[name])
+			default:
+				fmt.Fprintf(w, "%s\t%s\n", name, macros[name])
+			}
+		}
+		w.Flush()
+	},
+}
+
+var macroListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List dice macros",
+	Args:  cobra.NoArgs,
+	Run:   macroCmd.Run,
+}
+
+var macroAddCmd = &cobra.Command{
+	Use:   "add [name] [expression]",
+	Short: "Save a dice expression under a name, replacing any macro of that name",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, src := args[0], args[1]
+		if err := checkMacroName(name); err != nil {
+			log.Fatal("Invalid macro name: ", err)
+		}
+		_, exprSrc, err := splitRepetition(src)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if _, err := parseDice(exprSrc); err != nil {
+			log.Fatal("Invalid dice expression: ", err)
+		}
+
+		old, _, err := lookupMacro(name)
+		if err != nil {
+			log.Fatal("Failed to load macros:", err)
+		}
+		err = db.Update(func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists([]byte("macros"))
+			if err != nil {
+				return err
+			}
+			return b.Put([]byte(name), []byte(src))
+		})
+		if err != nil {
+			log.Fatal("Failed to save macro:", err)
+		}
+
+		if old != "" {
+			fmt.Printf("Updated macro '%s': %s (was %s)\n", name, src, old)
+		} else {
+			fmt.Printf("Added macro '%s': %s\n", name, src)
+		}
+	},
+}
+
+var macroDeleteCmd = &cobra.Command{
+	Use:   "delete [name]",
+	Short: "Delete a dice macro",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		src, ok, err := lookupMacro(name)
+		if err != nil {
+			log.Fatal("Failed to load macros:", err)
+		}
+		if !ok {
+			log.Fatalf("No macro named '%s'", name)
+		}
+
+		err = db.Update(func(tx *bolt.Tx) error {
+			if err := moveToTrash(tx, TrashItem{Kind: "macro", Name: name, Data: src}); err != nil {
+				return err
+			}
+			return tx.Bucket([]byte("macros")).Delete([]byte(name))
+		})
+		if err != nil {
+			log.Fatal("Failed to delete macro:", err)
+		}
+		fmt.Printf("Deleted macro '%s' (restore it with: roll trash restore %s)\n", name, shellArg(name))
+	},
+}
+
+func init() {
+	macroCmd.AddCommand(macroListCmd)
+	macroCmd.AddCommand(macroAddCmd)
+	macroCmd.AddCommand(macroDeleteCmd)
+	macroDeleteCmd.ValidArgsFunction = completeArgs(macroNames)
+}
+
+// checkMacroName makes sure a name cannot be mistaken for dice notation or
+// for something else roll dice understands
+func checkMacroName(name string) error {
+	if name == "" {
+		return fmt.Errorf("the name is empty")
+	}
+	for i, r := range name {
+		if !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r) && r != '-' && r != '_') {
+			return fmt.Errorf("'%s' must start with a letter and hold only letters, digits, - and _", name)
+		}
+	}
+	if _, err := parseDice(name); err == nil {
+		return fmt.Errorf("'%s' is already dice notation", name)
+	}
+
+	// roll dice runs its subcommands and repeats the last roll for these
+	// names, so a macro with one of them could never be rolled
+	reserved := []string{"last", "!!"}
+	for _, sub := range diceCmd.Commands() {
+		reserved = append(reserved, sub.Name())
+		reserved = append(reserved, sub.Aliases...)
+	}
+	for _, word := range reserved {
+		if name == word {
+			return fmt.Errorf("'%s' is reserved by roll dice", name)
+		}
+	}
+	return nil
+}
+
+// loadMacros returns every macro's expression by name
+func loadMacros() (map[string]string, error) {
+	macros := map[string]string{}
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("macros"))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			macros[string(k)] = string(v)
+			return nil
+		})
+	})
+	return macros, err
+}
+
+// lookupMacro returns the expression saved under name, if there is one
+func lookupMacro(name string) (string, bool, error) {
+	macros, err := loadMacros()
+	if err != nil {
+		return "", false, err
+	}
+	src, ok := macros[name]
+	return src, ok, nil
+}
+
+// macroNames returns the names of every macro, sorted
+func macroNames() []string {
+	macros, err := loadMacros()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(macros))
+	for name := range macros {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -2,12 +2,15 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"math/rand"
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/BurntSushi/toml"
@@ -22,31 +25,77 @@ type Config struct {
 	Grace    int    `toml:"grace"`
 	Pity     int    `toml:"pity"`
 	Variance int    `toml:"variance"`
+
+	// Grace accrues per failed roll by default, or per unit of time since
+	// the last success ("day", "12h", ...)
+	GracePer string `toml:"grace_per,omitempty"`
+
+	// Compound configs succeed when at least Require of Checks pass
+	Require int     `toml:"require,omitzero"`
+	Checks  []Check `toml:"checks,omitempty"`
+
+	// Only one config in a group may succeed until the group is reset
+	Group string `toml:"group,omitempty"`
+
+	// Tags are free-form labels for finding and cleaning up configs
+	Tags []string `toml:"tags,omitempty"`
+
+	// Every roll in a spark group counts towards a shared guarantee that
+	// can be redeemed once it reaches Spark
+	SparkGroup string `toml:"spark_group,omitempty"`
+	Spark      int    `toml:"spark,omitzero"`
+
+	// Degrees label rolls by how far they passed or missed, e.g. criticals
+	Degrees []Degree `toml:"degrees,omitempty"`
+
+	// Success overrides "roll <= chance" with an expression over the roll
+	// and state, e.g. "roll <= chance || roll == 100"
+	Success string `toml:"success,omitempty"`
 }
 
 // State represents the current state for a config
 type State struct {
-	PityCounter int `json:"pity_counter"`
-	LastRoll    int `json:"last_roll"`
+	PityCounter   int       `json:"pity_counter"`
+	LastRoll      int       `json:"last_roll"`
+	LastRolledAt  time.Time `json:"last_rolled_at"`
+	LastSuccessAt time.Time `json:"last_success_at"`
+	LastDegree    string    `json:"last_degree,omitempty"`
 }
 
 var (
 	db         *bolt.DB
 	configDir  string
 	dbPath     string
+	a11y       bool
+	verbose    bool
+	quiet      bool
+	rngSeed    int64
+	rng        *rand.Rand
 	rootCmd    = &cobra.Command{
 		Use:   "roll",
 		Short: "A probability-based roll system with pity mechanics",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if err := loadSettings(cmd); err != nil {
+				log.Fatal("Failed to load settings:", err)
+			}
+			if cmd.Flags().Changed("seed") {
+				rngSeed, _ = cmd.Flags().GetInt64("seed")
+				rng = rand.New(rand.NewSource(rngSeed))
+			}
+		},
 	}
 )
 
 func init() {
-	// Set up config directory
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		log.Fatal(err)
+	// Set up config directory, which ROLL_DIR can move (e.g. to a volume)
+	configDir = os.Getenv("ROLL_DIR")
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			log.Fatal(err)
+		}
+		configDir = filepath.Join(homeDir, ".roll")
 	}
-	configDir = filepath.Join(homeDir, ".roll")
 	dbPath = filepath.Join(configDir, "roll.db")
 
 	// Create config directory if it doesn't exist
@@ -54,8 +103,16 @@ func init() {
 		log.Fatal(err)
 	}
 
-	// Initialize random seed
-	rand.Seed(time.Now().UnixNano())
+	// Initialize random source, keeping the seed for --verbose output.
+	// --seed replaces it to repeat an earlier draw.
+	rngSeed = time.Now().UnixNano()
+	rng = rand.New(rand.NewSource(rngSeed))
+	rootCmd.PersistentFlags().Int64("seed", 0, "Seed the random draws to repeat an earlier result")
+
+	// Screen-reader friendly output without emoji or symbols
+	rootCmd.PersistentFlags().BoolVar(&a11y, "a11y", false, "Screen-reader friendly output")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Show where each random draw came from")
+	rootCmd.PersistentFlags().BoolVarP(&quiet, "quiet", "q", false, "Print only the outcome")
 
 	// Add commands
 	rootCmd.AddCommand(createCmd)
@@ -64,6 +121,41 @@ func init() {
 	rootCmd.AddCommand(showCmd)
 	rootCmd.AddCommand(deleteCmd)
 	rootCmd.AddCommand(diceCmd)
+	rootCmd.AddCommand(oddsCmd)
+	rootCmd.AddCommand(configCmd)
+	rootCmd.AddCommand(lintCmd)
+	rootCmd.AddCommand(tutorialCmd)
+	rootCmd.AddCommand(practiceCmd)
+	rootCmd.AddCommand(projectCmd)
+	rootCmd.AddCommand(badgeCmd)
+	rootCmd.AddCommand(groupCmd)
+	rootCmd.AddCommand(sparkCmd)
+	rootCmd.AddCommand(oracleCmd)
+	rootCmd.AddCommand(stockCmd)
+	rootCmd.AddCommand(npcCmd)
+	rootCmd.AddCommand(assignCmd)
+	rootCmd.AddCommand(teamsCmd)
+	rootCmd.AddCommand(santaCmd)
+	rootCmd.AddCommand(decideCmd)
+	rootCmd.AddCommand(rewardsCmd)
+	rootCmd.AddCommand(gateCmd)
+	rootCmd.AddCommand(bucketCmd)
+	rootCmd.AddCommand(sampleCmd)
+	rootCmd.AddCommand(narrativeCmd)
+	rootCmd.AddCommand(randCmd)
+	rootCmd.AddCommand(tokenCmd)
+	rootCmd.AddCommand(passwordCmd)
+	rootCmd.AddCommand(wordlistCmd)
+	rootCmd.AddCommand(coinCmd)
+	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(rangeCmd)
+	rootCmd.AddCommand(pickCmd)
+	rootCmd.AddCommand(trashCmd)
+	rootCmd.AddCommand(examplesCmd)
+	rootCmd.AddCommand(deckCmd)
+	rootCmd.AddCommand(recordCmd)
+	rootCmd.AddCommand(playbackCmd)
+	rootCmd.AddCommand(macroCmd)
 }
 
 var createCmd = &cobra.Command{
@@ -89,19 +181,7 @@ var createCmd = &cobra.Command{
 			log.Fatal("Invalid variance value:", err)
 		}
 
-		// Validate values
-		if chance < 0 || chance > 100 {
-			log.Fatal("Chance must be between 0 and 100")
-		}
-		if grace < 0 {
-			log.Fatal("Grace must be non-negative")
-		}
-		if pity < 0 {
-			log.Fatal("Pity must be non-negative")
-		}
-		if variance < 0 {
-			log.Fatal("Variance must be non-negative")
-		}
+		gracePer, _ := cmd.Flags().GetString("grace-per")
 
 		config := Config{
 			Name:     name,
@@ -109,6 +189,15 @@ var createCmd = &cobra.Command{
 			Grace:    grace,
 			Pity:     pity,
 			Variance: variance,
+			GracePer: gracePer,
+		}
+
+		// Validate values
+		if name == settingsName {
+			log.Fatalf("'%s' is reserved for the settings file", settingsName)
+		}
+		if errs := validateConfig(&config); len(errs) > 0 {
+			log.Fatal(errs[0])
 		}
 
 		// Save config to TOML file
@@ -124,28 +213,17 @@ var createCmd = &cobra.Command{
 		}
 
 		// Initialize state in database
-		err = db.Update(func(tx *bolt.Tx) error {
-			b, err := tx.CreateBucketIfNotExists([]byte("states"))
-			if err != nil {
-				return err
-			}
-
-			state := State{PityCounter: 0, LastRoll: 0}
-			data, err := json.Marshal(state)
-			if err != nil {
-				return err
-			}
-
-			return b.Put([]byte(name), data)
-		})
-
-		if err != nil {
+		if err := initState(name); err != nil {
 			log.Fatal("Failed to initialize state:", err)
 		}
 
+		if err := snapshotConfig(name); err != nil {
+			log.Fatal("Failed to record config version:", err)
+		}
+
 		fmt.Printf("Created roll configuration '%s' with:\n", name)
 		fmt.Printf("  Chance: %d%%\n", chance)
-		fmt.Printf("  Grace: %d%%\n", grace)
+		fmt.Printf("  Grace: %d%% per %s\n", grace, graceUnit(&config))
 		fmt.Printf("  Pity: %d rolls\n", pity)
 		fmt.Printf("  Variance: 1-%d chance of adding grace (%d%%)\n", variance, grace)
 		fmt.Printf("\nConfig saved to: %s\n", configPath)
@@ -155,9 +233,15 @@ var createCmd = &cobra.Command{
 var rollCmd = &cobra.Command{
 	Use:   "roll [name]",
 	Short: "Roll using a configuration",
-	Args:  cobra.ExactArgs(1),
+	Long: `Roll using a configuration, advancing its pity state.
+
+With --ci the result is printed as one line of JSON and the exit code is 2
+when the roll fails, so a pipeline step can gate on it. --state-file keeps
+the state in a JSON file instead of the database, for CI caches.`,
+	Args: cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		name := args[0]
+		stateFile, _ := cmd.Flags().GetString("state-file")
 
 		// Load config
 		config, err := loadConfig(name)
@@ -165,73 +249,27 @@ var rollCmd = &cobra.Command{
 			log.Fatal("Failed to load config:", err)
 		}
 
-		// Load state
-		var state State
-		err = db.Update(func(tx *bolt.Tx) error {
-			b := tx.Bucket([]byte("states"))
-			if b == nil {
-				return fmt.Errorf("states bucket not found")
-			}
-
-			data := b.Get([]byte(name))
-			if data == nil {
-				return fmt.Errorf("state not found for %s", name)
-			}
-
-			if err := json.Unmarshal(data, &state); err != nil {
-				return err
-			}
-
-			// Calculate effective chance
-			effectiveChance := config.Chance + (state.PityCounter * config.Grace)
-			
-			// Apply variance - adds grace value with 1/variance chance
-			if config.Variance > 0 {
-				varianceRoll := rand.Intn(config.Variance) + 1
-				if rand.Intn(varianceRoll) == 0 {
-					effectiveChance += config.Grace
-				}
-			}
-
-			// Cap at 100%
-			if effectiveChance > 100 {
-				effectiveChance = 100
+		var result RollResult
+		if stateFile != "" {
+			result, err = rollWithStateFile(name, config, stateFile)
+		} else {
+			// Record hand edits made since the last snapshot
+			if err := snapshotConfig(name); err != nil {
+				log.Fatal("Failed to record config version:", err)
 			}
+			result, err = rollAndRecord(name, config)
+		}
+		if err != nil {
+			log.Fatal("Failed to update state:", err)
+		}
 
-			// Roll
-			roll := rand.Intn(100) + 1
-			success := roll <= effectiveChance
-
-			fmt.Printf("\n🎲 Rolling '%s'...\n", name)
-			fmt.Printf("Base chance: %d%%\n", config.Chance)
-			fmt.Printf("Pity counter: %d\n", state.PityCounter)
-			fmt.Printf("Grace bonus: %d%%\n", state.PityCounter*config.Grace)
-			fmt.Printf("Effective chance: %d%%\n", effectiveChance)
-			fmt.Printf("Roll: %d\n", roll)
-
-			if success {
-				fmt.Printf("\n✅ SUCCESS! 🎉\n")
-				state.PityCounter = 0
-			} else {
-				fmt.Printf("\n❌ FAILED\n")
-				if state.PityCounter < config.Pity {
-					state.PityCounter++
-				}
+		if ci {
+			if err := printRollJSON(name, result); err != nil {
+				log.Fatal("Failed to print result:", err)
 			}
-
-			state.LastRoll = roll
-
-			// Save updated state
-			data, err = json.Marshal(state)
-			if err != nil {
-				return err
+			if !result.Success {
+				os.Exit(exitRollFailed)
 			}
-
-			return b.Put([]byte(name), data)
-		})
-
-		if err != nil {
-			log.Fatal("Failed to update state:", err)
 		}
 	},
 }
@@ -245,14 +283,30 @@ var listCmd = &cobra.Command{
 			log.Fatal("Failed to read config directory:", err)
 		}
 
-		fmt.Println("Available configurations:")
+		withOdds, _ := cmd.Flags().GetBool("with-odds")
+		table, _ := cmd.Flags().GetBool("table")
+
+		var w *tabwriter.Writer
+		if table {
+			w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "NAME\tCHANCE\tGRACE\tPITY\tVARIANCE\tCURRENT\tEFFECTIVE\tEXPECTED\tLAST ROLL")
+			defer w.Flush()
+		} else {
+			fmt.Println("Available configurations:")
+		}
 		for _, file := range files {
 			if filepath.Ext(file.Name()) == ".toml" {
 				name := file.Name()[:len(file.Name())-5]
+				if name == settingsName {
+					continue
+				}
 				
 				// Load config to show details
 				config, err := loadConfig(name)
 				if err != nil {
+					if strict {
+						log.Fatalf("Failed to load config '%s': %v", name, err)
+					}
 					continue
 				}
 
@@ -264,15 +318,53 @@ var listCmd = &cobra.Command{
 						data := b.Get([]byte(name))
 						if data != nil {
 							json.Unmarshal(data, &state)
+							return nil
 						}
 					}
+					if strict {
+						log.Fatalf("State not found for '%s'", name)
+					}
 					return nil
 				})
 
+				level := pityLevel(config, &state, time.Now())
+
+				if table {
+					odds, err := cachedOdds(name, rollChain(config, level), level)
+					if err != nil {
+						log.Fatal("Failed to compute odds:", err)
+					}
+					chance, err := successChance(config, level)
+					if err != nil {
+						log.Fatal("Failed to compute odds:", err)
+					}
+					expected := "never"
+					if !odds.Never {
+						expected = formatFloat(odds.Expected, settings.Precision)
+					}
+					fmt.Fprintf(w, "%s\t%d%%\t%d%%\t%d\t1-%d\t%d\t%s\t%s\t%s\n",
+						name, config.Chance, config.Grace, config.Pity, config.Variance,
+						level, formatPercent(chance), expected, since(state.LastRolledAt))
+					continue
+				}
+
 				fmt.Printf("\n  %s:\n", name)
 				fmt.Printf("    Chance: %d%% | Grace: %d%% | Pity: %d | Variance: 1-%d chance\n", 
 					config.Chance, config.Grace, config.Pity, config.Variance)
-				fmt.Printf("    Current pity: %d\n", state.PityCounter)
+				fmt.Printf("    Current pity: %d\n", level)
+
+				if withOdds {
+					odds, err := cachedOdds(name, rollChain(config, level), level)
+					if err != nil {
+						log.Fatal("Failed to compute odds:", err)
+					}
+					if odds.Never {
+						fmt.Printf("    Expected rolls: never succeeds\n")
+					} else {
+						fmt.Printf("    Expected rolls: %s | 90%% by roll %s\n",
+							formatFloat(odds.Expected, settings.Precision), formatCount(odds.RollsFor(0.9)))
+					}
+				}
 			}
 		}
 	},
@@ -290,134 +382,818 @@ var showCmd = &cobra.Command{
 			log.Fatal("Failed to load config:", err)
 		}
 
-		var state State
-		err = db.View(func(tx *bolt.Tx) error {
-			b := tx.Bucket([]byte("states"))
-			if b == nil {
-				return fmt.Errorf("states bucket not found")
-			}
-
-			data := b.Get([]byte(name))
-			if data == nil {
-				return fmt.Errorf("state not found")
-			}
-
-			return json.Unmarshal(data, &state)
-		})
-
+		state, err := viewState(name)
 		if err != nil {
 			log.Fatal("Failed to load state:", err)
 		}
 
 		fmt.Printf("Configuration '%s':\n", name)
 		fmt.Printf("  Base chance: %d%%\n", config.Chance)
-		fmt.Printf("  Grace: %d%% per fail\n", config.Grace)
+		fmt.Printf("  Grace: %d%% per %s\n", config.Grace, graceUnit(config))
 		fmt.Printf("  Max pity: %d rolls\n", config.Pity)
 		fmt.Printf("  Variance: 1-%d chance of adding grace (%d%%)\n", config.Variance, config.Grace)
+		if len(config.Checks) > 0 {
+			fmt.Printf("  Checks (need %d of %d):\n", requiredChecks(config), len(config.Checks))
+			for _, check := range config.Checks {
+				fmt.Printf("    %s: %d%%\n", check.Name, check.Chance)
+			}
+		}
+		if config.Group != "" {
+			lock, err := loadGroupState(config.Group)
+			if err != nil {
+				log.Fatal("Failed to load group state:", err)
+			}
+			if !lock.Locked(time.Now()) {
+				fmt.Printf("  Group: %s (open)\n", config.Group)
+			} else {
+				fmt.Printf("  Group: %s (locked by '%s')\n", config.Group, lock.LockedBy)
+			}
+		}
+		if len(config.Tags) > 0 {
+			fmt.Printf("  Tags: %s\n", strings.Join(config.Tags, ", "))
+		}
 		fmt.Printf("\nCurrent state:\n")
-		fmt.Printf("  Pity counter: %d\n", state.PityCounter)
-		fmt.Printf("  Current chance: %d%%\n", config.Chance+(state.PityCounter*config.Grace))
+		level := pityLevel(config, state, time.Now())
+		fmt.Printf("  Pity counter: %d\n", level)
+		chance, err := passChance(config, level, level*config.Grace)
+		if err != nil {
+			log.Fatal("Failed to evaluate success expression:", err)
+		}
+		fmt.Printf("  Current chance: %d%%\n", percent(chance))
 		fmt.Printf("  Last roll: %d\n", state.LastRoll)
+		if state.LastDegree != "" {
+			fmt.Printf("  Last degree: %s\n", state.LastDegree)
+		}
+		if !state.LastRolledAt.IsZero() {
+			fmt.Printf("  Last rolled at: %s\n", formatTime(state.LastRolledAt))
+		}
 		fmt.Printf("\nConfig file: %s\n", filepath.Join(configDir, name+".toml"))
 	},
 }
 
 var deleteCmd = &cobra.Command{
 	Use:   "delete [name]",
-	Short: "Delete a roll configuration",
-	Args:  cobra.ExactArgs(1),
+	Short: "Delete a roll configuration, or every one matching filters",
+	Long: `Delete a configuration by name, or every configuration matching --tag
+and --older-than:
+
+  roll delete coffee
+  roll delete --tag old-event --older-than 180d --dry-run
+
+--older-than counts from the last roll, or from the last change to the
+config file if it was never rolled, and takes days (180d), weeks (26w) or a
+duration like 72h. Deleted configurations go to the trash (see roll trash).`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		name := args[0]
+		tag, _ := cmd.Flags().GetString("tag")
+		olderThan, _ := cmd.Flags().GetString("older-than")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
 
-		// Delete config file
-		configPath := filepath.Join(configDir, name+".toml")
-		if err := os.Remove(configPath); err != nil {
-			log.Fatal("Failed to delete config file:", err)
+		names := args
+		if len(args) == 1 {
+			if tag != "" || olderThan != "" {
+				log.Fatal("Give a configuration name or filters, not both")
+			}
+			if _, err := os.Stat(filepath.Join(configDir, args[0]+".toml")); err != nil {
+				log.Fatal("Failed to read config file:", err)
+			}
+		} else {
+			if tag == "" && olderThan == "" {
+				log.Fatal("Give a configuration name, or --tag or --older-than to delete several")
+			}
+			age, err := parseAge(olderThan)
+			if err != nil {
+				log.Fatal("Invalid --older-than: ", err)
+			}
+			names, err = matchingConfigs(tag, age)
+			if err != nil {
+				log.Fatal("Failed to filter configurations:", err)
+			}
+			if len(names) == 0 {
+				fmt.Println("No configurations match")
+				return
+			}
 		}
 
-		// Delete state from database
-		err := db.Update(func(tx *bolt.Tx) error {
-			b := tx.Bucket([]byte("states"))
-			if b != nil {
-				return b.Delete([]byte(name))
+		if dryRun {
+			fmt.Printf("Would delete %s:\n", plural(len(names), "configuration", "configurations"))
+			for _, name := range names {
+				fmt.Printf("  %s\n", name)
 			}
-			return nil
-		})
-
-		if err != nil {
-			log.Fatal("Failed to delete state:", err)
+			return
 		}
 
-		fmt.Printf("Deleted configuration '%s'\n", name)
+		for _, name := range names {
+			if err := deleteConfig(name); err != nil {
+				log.Fatalf("Failed to delete '%s': %v", name, err)
+			}
+			fmt.Printf("Deleted configuration '%s'\n", name)
+		}
+		if len(names) == 1 {
+			fmt.Printf("Restore it with: roll trash restore %s\n", shellArg(names[0]))
+		} else {
+			fmt.Println("Restore any of them with: roll trash restore [name]")
+		}
 	},
 }
 
 var diceCmd = &cobra.Command{
-	Use:   "dice [type]",
-	Short: "Roll dice (d4, d5, d6, d8, d10, d12, d20, d100)",
-	Args:  cobra.ExactArgs(1),
+	Use:   "dice [expression]",
+	Short: "Roll dice in standard notation like 3d6+2, optionally repeated as 6x(3d6)",
+	Long: `Roll dice in standard notation: dice like 3d6 or d20 and numbers, joined
+by + and -, e.g. 3d6+2, 2d8-1 or d20+5. Prefix with a count such as
+6x(3d6) to roll the whole expression several times.
+
+Add ! to make dice explode: d6! rolls again and adds whenever it shows a 6,
+up to --explode-cap extra rolls per die.
+
+Keep or drop dice with kh, kl, dh and dl: 4d6kh3 keeps the highest three,
+4d6dl1 drops the lowest one.
+
+Fudge dice such as 4dF show -1, 0 or +1 and are drawn as -, blank and +;
+add a skill as a number, as in 4dF+2.
+
+Digit dice such as d66 and d666 roll one d6 per digit and read them as a
+number from 11 to 66 or 111 to 666, for tables. d% rolls percentile dice
+as a tens die and a ones die and shows how they add up, e.g. 70 + 3 = 73
+(00 + 0 is 100).
+
+Reroll with r: 2d6r1 rerolls 1s once and d6r<3 rerolls anything below 3
+once; rr keeps rerolling until the die no longer matches, as in d6rr<3.
+Rerolls come before any explosion and after ! in the notation, as in d6!r1.
+
+Count successes instead of summing with --target: 7d10 --target 8 scores a
+success for every die showing 8 or more. --double 10 makes 10s count twice,
+--botch lets each 1 cancel a success (no successes and any 1 is a botch),
+and exploding dice such as 7d10! add their extra rolls to the pool.
+--glitch flags a glitch when more than half the dice show 1, and a critical
+glitch when that comes with no hits; --system shadowrun is short for
+--target 5 --glitch, as in 12d6 --system shadowrun.
+
+--wild d6 rolls a Savage Worlds wild die alongside a single trait die, as
+in d8+1 --wild d6: both explode and the higher one counts.
+
+Roll a macro saved with macro add by its name, as in roll dice attack.
+
+Use --last or "!!" to repeat the previous roll; flags given alongside
+override the ones it was rolled with.`,
+	Args: cobra.MaximumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
-		diceType := args[0]
+		// Repeat the previous roll with --last or !!
+		last, _ := cmd.Flags().GetBool("last")
+		if last || (len(args) == 1 && args[0] == "!!") {
+			if last && len(args) == 1 {
+				log.Fatal("--last cannot be combined with a dice type")
+			}
+			record, err := lastDice()
+			if err != nil {
+				log.Fatal("Failed to load dice history:", err)
+			}
+			if record == nil {
+				log.Fatal("No dice rolls to repeat yet")
+			}
+			args = []string{record.Expr}
+			if !cmd.Flags().Changed("shift") {
+				cmd.Flags().Set("shift", strconv.Itoa(record.Shift))
+			}
+			if !cmd.Flags().Changed("label") {
+				cmd.Flags().Set("label", record.Label)
+			}
+			if !cmd.Flags().Changed("explode-cap") && record.ExplodeCap != nil {
+				cmd.Flags().Set("explode-cap", strconv.Itoa(*record.ExplodeCap))
+			}
+			if !cmd.Flags().Changed("adv") && !cmd.Flags().Changed("dis") {
+				cmd.Flags().Set("adv", strconv.FormatBool(record.Adv > 0))
+				cmd.Flags().Set("dis", strconv.FormatBool(record.Adv < 0))
+			}
+			if !cmd.Flags().Changed("wild") && record.Wild != "" {
+				cmd.Flags().Set("wild", record.Wild)
+			}
+			if !cmd.Flags().Changed("target") && record.Pool != nil {
+				cmd.Flags().Set("target", strconv.Itoa(record.Pool.Target))
+				if !cmd.Flags().Changed("double") {
+					cmd.Flags().Set("double", strconv.Itoa(record.Pool.Double))
+				}
+				if !cmd.Flags().Changed("botch") {
+					cmd.Flags().Set("botch", strconv.FormatBool(record.Pool.Botch))
+				}
+				if !cmd.Flags().Changed("glitch") {
+					cmd.Flags().Set("glitch", strconv.FormatBool(record.Pool.Glitch))
+				}
+			}
+		}
+		if len(args) == 0 {
+			log.Fatal("Specify a dice expression, or --last to repeat the previous roll")
+		}
+
+		// A macro name rolls the expression saved under it
+		src := args[0]
+		macro, isMacro, err := lookupMacro(src)
+		if err != nil {
+			log.Fatal("Failed to load macros:", err)
+		}
+		if isMacro {
+			src = macro
+			if label, _ := cmd.Flags().GetString("label"); label == "" {
+				cmd.Flags().Set("label", args[0])
+			}
+		}
+
+		// Split off a repetition prefix such as 6x(d6)
+		count, exprSrc, err := splitRepetition(src)
+		if err != nil {
+			log.Fatal(err)
+		}
 		
-		// Get shift value from flag
+		// Get shift value and label from flags
 		shift, _ := cmd.Flags().GetInt("shift")
+		label, _ := cmd.Flags().GetString("label")
+		adv := 0
+		if b, _ := cmd.Flags().GetBool("adv"); b {
+			adv = 1
+		}
+		if b, _ := cmd.Flags().GetBool("dis"); b {
+			adv = -1
+		}
 		
-		var sides int
-		
-		// Parse dice type
-		switch diceType {
-		case "d10", "D10":
-			sides = 10
-		case "d5", "D5":
-			sides = 5
-		case "d4", "D4":
-			sides = 4
-		case "d6", "D6":
-			sides = 6
-		case "d8", "D8":
-			sides = 8
-		case "d12", "D12":
-			sides = 12
-		case "d20", "D20":
-			sides = 20
-		case "d100", "D100":
-			sides = 100
+		expr, err := parseDice(exprSrc)
+		if err != nil {
+			log.Fatal("Invalid dice expression: ", err)
+		}
+		if cmd.Flags().Changed("explode-cap") {
+			expr.ExplodeCap, _ = cmd.Flags().GetInt("explode-cap")
+			if expr.ExplodeCap < 0 || expr.ExplodeCap > maxDice {
+				log.Fatalf("Explode cap must be between 0 and %d", maxDice)
+			}
+		}
+
+		// A system presets the pool rules it is known for
+		switch system, _ := cmd.Flags().GetString("system"); system {
+		case "":
+		case "shadowrun":
+			if !cmd.Flags().Changed("target") {
+				cmd.Flags().Set("target", "5")
+			}
+			cmd.Flags().Set("glitch", "true")
 		default:
-			log.Fatal("Invalid dice type. Supported: d4, d5, d6, d8, d10, d12, d20, d100")
+			log.Fatalf("Unknown system '%s'; the only system is shadowrun", system)
 		}
-		
-		// Roll the dice
-		roll := rand.Intn(sides) + 1
-		
-		fmt.Printf("\n🎲 Rolling %s...\n", diceType)
-		fmt.Printf("Roll: %d\n", roll)
-		
-		if shift != 0 {
-			result := roll + shift
-			fmt.Printf("Shifted result: %d (roll + %d)\n", result, shift)
-			fmt.Printf("\nRange for %s with shift: %d-%d\n", diceType, 1+shift, sides+shift)
-		} else {
-			fmt.Printf("\nStandard range for %s: 1-%d\n", diceType, sides)
+
+		// A target turns the roll into a pool that counts successes
+		var pool *Pool
+		if target, _ := cmd.Flags().GetInt("target"); target != 0 {
+			pool = &Pool{Target: target}
+			pool.Double, _ = cmd.Flags().GetInt("double")
+			pool.Botch, _ = cmd.Flags().GetBool("botch")
+			pool.Glitch, _ = cmd.Flags().GetBool("glitch")
+			if err := pool.check(expr); err != nil {
+				log.Fatal("Invalid dice pool: ", err)
+			}
+			if adv != 0 || shift != 0 || count > 1 {
+				log.Fatal("--adv, --dis, --shift and repetitions do not apply to a dice pool")
+			}
+		} else if cmd.Flags().Changed("double") || cmd.Flags().Changed("botch") || cmd.Flags().Changed("glitch") {
+			log.Fatal("--double, --botch and --glitch need --target")
+		}
+
+		// A wild die rolls alongside the trait die and the higher counts
+		wildSrc, _ := cmd.Flags().GetString("wild")
+		var wild DiceTerm
+		trait := -1
+		if wildSrc != "" {
+			if wild, err = parseWildDie(wildSrc); err != nil {
+				log.Fatal("Invalid wild die: ", err)
+			}
+			if trait, err = wildTrait(expr); err != nil {
+				log.Fatal("Invalid dice expression: ", err)
+			}
+			if pool != nil || adv != 0 || count > 1 {
+				log.Fatal("--wild cannot be combined with pools, --adv, --dis or repetitions")
+			}
+		}
+
+		// Odds and target numbers are worked out instead of rolling
+		showOdds, _ := cmd.Flags().GetBool("odds")
+		dcs, _ := cmd.Flags().GetIntSlice("dc")
+		trials, _ := cmd.Flags().GetInt("trials")
+		if trials < 0 {
+			log.Fatal("Trials must be non-negative")
+		}
+		if trials > 0 && len(dcs) == 0 {
+			log.Fatal("--trials needs --dc")
+		}
+		if pool != nil && (showOdds || len(dcs) > 0) {
+			odds, err := pool.Distribution(expr)
+			if err != nil {
+				log.Fatal(err)
+			}
+			dist := odds.Successes
+			name := expr.String() + " " + pool.String()
+			if showOdds {
+				printDiceOdds(name, dist)
+				if pool.Botch && !quiet {
+					fmt.Printf("\nBotch: %s\n", formatPercent(odds.Botch))
+				}
+				if pool.Glitch && !quiet {
+					fmt.Printf("\nGlitch: %s\n", formatPercent(odds.Glitch))
+					fmt.Printf("Critical glitch: %s\n", formatPercent(odds.CriticalGlitch))
+				}
+			}
+			if len(dcs) > 0 {
+				if showOdds && !quiet {
+					fmt.Println()
+				}
+				sample := func() int { return pool.Count(expr.Roll()).Net }
+				printDCChances(name, dist, dcs, sample, trials)
+			}
+			return
+		}
+		if trait >= 0 && (showOdds || len(dcs) > 0) {
+			dist := convolve(wildDistribution(expr, trait, wild), Distribution{shift: 1})
+			name := fmt.Sprintf("%s with a d%d wild die", expr, wild.Sides)
+			if showOdds {
+				printDiceOdds(name, dist)
+			}
+			if len(dcs) > 0 {
+				if showOdds && !quiet {
+					fmt.Println()
+				}
+				sample := func() int { return rollWild(expr, trait, wild).Total() + shift }
+				printDCChances(name, dist, dcs, sample, trials)
+			}
+			return
 		}
+		if showOdds || len(dcs) > 0 {
+			dist, err := expr.Distribution()
+			if err != nil {
+				log.Fatal(err)
+			}
+			dist = convolve(dist.Advantage(adv), Distribution{shift: 1})
+			name := expr.String() + advantageName(adv)
+			if showOdds {
+				printDiceOdds(name, dist)
+			}
+			if len(dcs) > 0 {
+				if showOdds && !quiet {
+					fmt.Println()
+				}
+				sample := func() int {
+					roll, _ := rollAdvantage(expr, adv)
+					return roll.Total + shift
+				}
+				printDCChances(name, dist, dcs, sample, trials)
+			}
+			return
+		}
+
+		record := DiceRecord{Expr: args[0], Shift: shift, Label: label, Adv: adv, Pool: pool, Wild: wildSrc, RolledAt: time.Now().UTC()}
+		if isMacro && label == args[0] {
+			record.Label = ""
+		}
+		if cmd.Flags().Changed("explode-cap") {
+			record.ExplodeCap = &expr.ExplodeCap
+		}
+		if err := recordDice(record); err != nil {
+			log.Fatal("Failed to save dice history:", err)
+		}
+
+		if count > 1 {
+			rollRepeated(label, expr, adv, shift, count)
+			return
+		}
+
+		name := expr.String() + advantageName(adv)
+		if label != "" {
+			name = fmt.Sprintf("%s (%s)", name, label)
+		}
+		if pool != nil {
+			printPoolRoll(name, expr.Roll(), *pool)
+			return
+		}
+		if trait >= 0 {
+			printWildRoll(name, expr, trait, wild, rollWild(expr, trait, wild), shift)
+			return
+		}
+		roll, dropped := rollAdvantage(expr, adv)
+		printDiceRoll(name, roll, dropped, shift)
 	},
 }
 
 func init() {
 	// Add shift flag to dice command
 	diceCmd.Flags().IntP("shift", "s", 0, "Shift the dice result by this amount")
+	diceCmd.Flags().StringP("label", "l", "", "Label to show with the results")
+	diceCmd.Flags().Bool("odds", false, "Show the exact distribution instead of rolling")
+	diceCmd.Flags().IntSlice("dc", nil, "Show the chance of meeting these targets instead of rolling, e.g. 10,15,20")
+	diceCmd.Flags().Bool("last", false, "Repeat the previous dice roll")
+	diceCmd.Flags().Bool("adv", false, "Roll twice and keep the higher total (advantage)")
+	diceCmd.Flags().Bool("dis", false, "Roll twice and keep the lower total (disadvantage)")
+	diceCmd.MarkFlagsMutuallyExclusive("adv", "dis")
+	diceCmd.Flags().Int("explode-cap", defaultExplodeCap, "Most extra rolls one exploding die (d6!) may add")
+	diceCmd.Flags().Int("target", 0, "Count dice showing at least this as successes instead of summing")
+	diceCmd.Flags().Int("double", 0, "With --target, faces of at least this count as two successes")
+	diceCmd.Flags().Bool("botch", false, "With --target, let each 1 cancel a success and flag botches")
+	diceCmd.Flags().Bool("glitch", false, "With --target, flag a glitch when more than half the dice show 1")
+	diceCmd.Flags().String("wild", "", "Roll this wild die (e.g. d6) with the trait die and keep the higher, both exploding")
+	diceCmd.Flags().String("system", "", "Use a game's pool rules: shadowrun (5s and 6s hit, glitches on 1s)")
+	diceCmd.Flags().Int("trials", 0, "With --dc, also simulate this many rolls to check the exact chances")
+	diceCmd.ValidArgsFunction = completeArgs(diceSuggestions)
+	diceCmd.RegisterFlagCompletionFunc("wild", completeFlag(func() []string { return []string{"d4", "d6", "d8", "d10", "d12"} }))
+
+	// Add time grace flag to create command
+	createCmd.Flags().String("grace-per", "", "Accrue grace per unit of time since the last success (hour, day, week or a duration like 12h) instead of per failed roll")
+
+	// Add state file flag to roll command
+	rollCmd.Flags().String("state-file", "", "Keep state in this JSON file instead of the database, e.g. for CI caches")
+
+	// Complete configuration names for commands that take one
+	for _, cmd := range []*cobra.Command{rollCmd, showCmd, deleteCmd} {
+		cmd.ValidArgsFunction = completeArgs(configNames)
+	}
+
+	// Add filter flags to delete command
+	deleteCmd.Flags().String("tag", "", "Delete every configuration with this tag")
+	deleteCmd.Flags().String("older-than", "", "Delete configurations not rolled for this long, e.g. 180d")
+	deleteCmd.Flags().Bool("dry-run", false, "List what would be deleted without deleting it")
+
+	// Add odds flag to list command
+	listCmd.Flags().Bool("with-odds", false, "Show expected rolls until success")
+	listCmd.Flags().Bool("table", false, "Show configurations as a table with computed columns")
 }
 
 func loadConfig(name string) (*Config, error) {
 	configPath := filepath.Join(configDir, name+".toml")
 	var config Config
 	
-	if _, err := toml.DecodeFile(configPath, &config); err != nil {
+	meta, err := toml.DecodeFile(configPath, &config)
+	if err != nil {
 		return nil, err
 	}
 
+	// Typos decode to zero values, so call out keys that matched nothing
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		msg := fmt.Sprintf("unknown keys in %s: %s", configPath, describeUnknownKeys(undecoded, tomlKeys(Config{})))
+		if strict {
+			return nil, fmt.Errorf("%s", msg)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+	}
+
 	return &config, nil
 }
 
+// RollResult is the outcome of a single roll against a config
+type RollResult struct {
+	Pity            int
+	EffectiveChance int
+	VarianceRoll    int
+	VarianceDraw    int
+	Draw            int
+	Roll            int
+	Success         bool
+	Margin          int
+	Degree          string
+	Checks          []CheckResult
+}
+
+// rollConfig rolls once against config and advances state accordingly
+func rollConfig(config *Config, state *State) RollResult {
+	return rollConfigAt(config, state, time.Now().UTC())
+}
+
+// rollConfigAt is rollConfig as if rolled at now, which time grace depends on
+func rollConfigAt(config *Config, state *State, now time.Time) RollResult {
+	// Time grace starts accruing on the first roll of a hand-written config
+	if interval, _ := graceInterval(config); interval > 0 && state.LastSuccessAt.IsZero() {
+		state.LastSuccessAt = now
+	}
+
+	level := pityLevel(config, state, now)
+	result := RollResult{Pity: level, VarianceDraw: -1}
+
+	// Calculate grace bonus
+	bonus := level * config.Grace
+
+	// Apply variance - adds grace value with 1/variance chance
+	if config.Variance > 0 {
+		result.VarianceRoll = rng.Intn(config.Variance) + 1
+		result.VarianceDraw = rng.Intn(result.VarianceRoll)
+		if result.VarianceDraw == 0 {
+			bonus += config.Grace
+		}
+	}
+
+	if len(config.Checks) > 0 {
+		// Compound configs roll every check; the roll is the number passed
+		checks, passed := rollChecks(config, bonus)
+		result.Checks = checks
+		chance, err := passChance(config, level, bonus)
+		if err != nil {
+			log.Fatal("Failed to evaluate success expression:", err)
+		}
+		result.EffectiveChance = percent(chance)
+		result.Roll = passed
+		result.Success = passed >= requiredChecks(config)
+	} else {
+		// Cap at 100%
+		result.EffectiveChance = min(config.Chance+bonus, 100)
+
+		// Roll
+		result.Draw = rng.Intn(100)
+		result.Roll = result.Draw + 1
+		success, err := rollSucceeds(config, result.Roll, result.EffectiveChance, level)
+		if err != nil {
+			log.Fatal("Failed to evaluate success expression:", err)
+		}
+		result.Success = success
+		result.Margin = result.EffectiveChance - result.Roll
+		result.Degree = matchDegree(config, result.Margin)
+	}
+
+	if result.Success {
+		state.PityCounter = 0
+		state.LastSuccessAt = now
+	} else if state.PityCounter < config.Pity {
+		state.PityCounter++
+	}
+
+	state.LastRoll = result.Roll
+	state.LastRolledAt = now
+	state.LastDegree = result.Degree
+
+	return result
+}
+
+// printRoll prints a roll result in the current output mode
+func printRoll(name string, config *Config, result RollResult) {
+	// Machine mode prints its JSON once the roll is saved
+	if ci {
+		return
+	}
+
+	if verbose {
+		printProvenance()
+		if config.Variance > 0 {
+			fmt.Printf("Variance draw: 1-in-%d (from 1-%d), drew %d, grace bonus applied: %t\n",
+				result.VarianceRoll, config.Variance, result.VarianceDraw, result.VarianceDraw == 0)
+		}
+		if len(result.Checks) == 0 {
+			fmt.Printf("Raw draw: %d of [0, 100), roll = %d, success if roll <= %d\n",
+				result.Draw, result.Roll, result.EffectiveChance)
+		}
+	}
+
+	if quiet {
+		outcome := "failure"
+		if result.Success {
+			outcome = "success"
+		}
+		if result.Degree != "" {
+			outcome += " (" + result.Degree + ")"
+		}
+		fmt.Println(outcome)
+		return
+	}
+
+	if a11y {
+		// Lead with the outcome, then the numbers behind it
+		outcome := "failure"
+		if result.Success {
+			outcome = "success"
+		}
+		if result.Degree != "" {
+			outcome += ", " + result.Degree
+		}
+		fmt.Printf("Rolling %s. Result: %s.\n", name, outcome)
+		if len(result.Checks) > 0 {
+			printChecks(config, result.Checks)
+			fmt.Printf("Overall chance %d percent, pity counter %d, grace bonus %d percent.\n",
+				result.EffectiveChance, result.Pity, result.Pity*config.Grace)
+			return
+		}
+		fmt.Printf("Rolled %d against an effective chance of %d percent.\n", result.Roll, result.EffectiveChance)
+		fmt.Printf("Base chance %d percent, pity counter %d, grace bonus %d percent.\n",
+			config.Chance, result.Pity, result.Pity*config.Grace)
+		return
+	}
+
+	fmt.Printf("\n🎲 Rolling '%s'...\n", name)
+	if len(result.Checks) == 0 {
+		fmt.Printf("Base chance: %d%%\n", config.Chance)
+	}
+	fmt.Printf("Pity counter: %d\n", result.Pity)
+	fmt.Printf("Grace bonus: %d%%\n", result.Pity*config.Grace)
+	fmt.Printf("Effective chance: %d%%\n", result.EffectiveChance)
+	if len(result.Checks) > 0 {
+		printChecks(config, result.Checks)
+	} else {
+		fmt.Printf("Roll: %d\n", result.Roll)
+	}
+
+	if result.Success {
+		fmt.Printf("\n✅ SUCCESS! 🎉\n")
+	} else {
+		fmt.Printf("\n❌ FAILED\n")
+	}
+	if result.Degree != "" {
+		fmt.Printf("%s (margin %+d)\n", strings.ToUpper(result.Degree), result.Margin)
+	}
+}
+
+// validateConfig checks a config's values and returns every problem found
+func validateConfig(config *Config) []error {
+	var errs []error
+	if config.Chance < 0 || config.Chance > 100 {
+		errs = append(errs, fmt.Errorf("Chance must be between 0 and 100"))
+	}
+	if config.Grace < 0 {
+		errs = append(errs, fmt.Errorf("Grace must be non-negative"))
+	}
+	if config.Pity < 0 {
+		errs = append(errs, fmt.Errorf("Pity must be non-negative"))
+	}
+	if config.Variance < 0 {
+		errs = append(errs, fmt.Errorf("Variance must be non-negative"))
+	}
+	if _, err := graceInterval(config); err != nil {
+		errs = append(errs, err)
+	}
+	errs = append(errs, validateChecks(config)...)
+	errs = append(errs, validateDegrees(config)...)
+	errs = append(errs, validateSuccess(config)...)
+	if (config.SparkGroup == "") != (config.Spark == 0) {
+		errs = append(errs, fmt.Errorf("Spark group and spark must be set together"))
+	}
+	if config.Spark < 0 {
+		errs = append(errs, fmt.Errorf("Spark must be non-negative"))
+	}
+	return errs
+}
+
+// configNames returns the names of all configs in the config directory
+func configNames() []string {
+	files, err := os.ReadDir(configDir)
+	if err != nil {
+		log.Fatal("Failed to read config directory:", err)
+	}
+
+	var names []string
+	for _, file := range files {
+		name := strings.TrimSuffix(file.Name(), ".toml")
+		if filepath.Ext(file.Name()) == ".toml" && name != settingsName {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// errNoState is returned by loadState for a config that has never been rolled
+var errNoState = errors.New("state not found")
+
+func loadState(name string) (*State, error) {
+	var state State
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("states"))
+		if b == nil {
+			return errNoState
+		}
+
+		data := b.Get([]byte(name))
+		if data == nil {
+			return errNoState
+		}
+
+		return json.Unmarshal(data, &state)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+// viewState is loadState for commands that only display a config. Unless
+// strict, a hand-written config that was never rolled shows the fresh state
+// roll would start it from.
+func viewState(name string) (*State, error) {
+	state, err := loadState(name)
+	if errors.Is(err, errNoState) && !strict {
+		return &State{}, nil
+	}
+	return state, err
+}
+
+// initState stores the starting state of a new config
+func initState(name string) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("states"))
+		if err != nil {
+			return err
+		}
+
+		// Time grace starts accruing from creation
+		state := State{PityCounter: 0, LastRoll: 0, LastSuccessAt: time.Now().UTC()}
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(name), data)
+	})
+}
+
+// rollAndRecord rolls a config, prints the result and saves the new state,
+// applying group locks and spark counters along the way
+func rollAndRecord(name string, config *Config) (RollResult, error) {
+	// Load state
+	var state State
+	var result RollResult
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("states"))
+		if b == nil {
+			if strict {
+				return fmt.Errorf("states bucket not found")
+			}
+			var err error
+			if b, err = tx.CreateBucket([]byte("states")); err != nil {
+				return err
+			}
+		}
+
+		// Hand-written configs start from a fresh state unless strict
+		data := b.Get([]byte(name))
+		if data == nil {
+			if strict {
+				return fmt.Errorf("state not found for %s", name)
+			}
+		} else if err := json.Unmarshal(data, &state); err != nil {
+			return err
+		}
+
+		if err := checkGroupLock(tx, config); err != nil {
+			return err
+		}
+
+		result = rollConfig(config, &state)
+		printRoll(name, config, result)
+
+		if result.Success && config.Group != "" {
+			if err := lockGroup(tx, config.Group, name); err != nil {
+				return err
+			}
+			if !quiet && !ci {
+				fmt.Printf("\nGroup '%s' is now locked until reset\n", config.Group)
+			}
+		}
+
+		if config.SparkGroup != "" {
+			spark, err := addSpark(tx, config)
+			if err != nil {
+				return err
+			}
+			if !quiet && !ci {
+				fmt.Printf("\nSpark '%s': %d/%d\n", config.SparkGroup, spark.Count, config.Spark)
+			}
+			if spark.Count >= config.Spark && !quiet && !ci {
+				fmt.Printf("Ready to redeem with: roll spark redeem [name]\n")
+			}
+		}
+
+		// Save updated state
+		data, err := json.Marshal(state)
+		if err != nil {
+			return err
+		}
+
+		return b.Put([]byte(name), data)
+	})
+	return result, err
+}
+
+// printProvenance reports which random source produced the draws that follow
+func printProvenance() {
+	fmt.Printf("RNG: math/rand, seed %d\n", rngSeed)
+}
+
+// since formats the time elapsed since t for display
+func since(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return fmt.Sprintf("%dm ago", int(d.Minutes()))
+	case d < 24*time.Hour:
+		return fmt.Sprintf("%dh ago", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd ago", int(d.Hours()/24))
+	}
+}
+
 func main() {
 	// Open database
 	var err error
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	"github.com/spf13/cobra"
+)
+
+// NarrativeFace is the symbols on one face of a narrative die. Failures and
+// threats are negative successes and advantages; a triumph also counts as a
+// success and a despair as a failure.
+type NarrativeFace struct {
+	Success   int
+	Advantage int
+	Triumph   int
+	Despair   int
+}
+
+// narrativeDie is one kind of Genesys die and its faces
+type narrativeDie struct {
+	Letter byte
+	Name   string
+	Faces  []NarrativeFace
+}
+
+var (
+	blankFace = NarrativeFace{}
+	sFace     = NarrativeFace{Success: 1}
+	ssFace    = NarrativeFace{Success: 2}
+	aFace     = NarrativeFace{Advantage: 1}
+	aaFace    = NarrativeFace{Advantage: 2}
+	saFace    = NarrativeFace{Success: 1, Advantage: 1}
+	fFace     = NarrativeFace{Success: -1}
+	ffFace    = NarrativeFace{Success: -2}
+	tFace     = NarrativeFace{Advantage: -1}
+	ttFace    = NarrativeFace{Advantage: -2}
+	ftFace    = NarrativeFace{Success: -1, Advantage: -1}
+)
+
+// narrativeDice lists the dice in the order results are shown
+var narrativeDice = []narrativeDie{
+	{'p', "proficiency", []NarrativeFace{blankFace, sFace, sFace, ssFace, ssFace, aFace, saFace, saFace, saFace, aaFace, aaFace, {Success: 1, Triumph: 1}}},
+	{'a', "ability", []NarrativeFace{blankFace, sFace, sFace, ssFace, aFace, aFace, saFace, aaFace}},
+	{'b', "boost", []NarrativeFace{blankFace, blankFace, sFace, saFace, aaFace, aFace}},
+	{'c', "challenge", []NarrativeFace{blankFace, fFace, fFace, ffFace, ffFace, tFace, tFace, ftFace, ftFace, ttFace, ttFace, {Success: -1, Despair: 1}}},
+	{'d', "difficulty", []NarrativeFace{blankFace, fFace, ffFace, tFace, tFace, tFace, ttFace, ftFace}},
+	{'s', "setback", []NarrativeFace{blankFace, blankFace, fFace, fFace, tFace, tFace}},
+}
+
+var narrativeCmd = &cobra.Command{
+	Use:   "narrative [pool]",
+	Short: "Roll Genesys / Star Wars narrative dice and net out the symbols",
+	Long: `Roll a pool of narrative dice written as counts and letters:
+
+  p  proficiency   a  ability   b  boost
+  c  challenge     d  difficulty   s  setback
+
+so "2a1p2d1s" (or "aapdds") rolls two ability, one proficiency, two
+difficulty and one setback die. Successes cancel failures and advantages
+cancel threats; the check succeeds with at least one net success. Triumphs
+count as a success and despairs as a failure, but neither is cancelled.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		showOdds, _ := cmd.Flags().GetBool("odds")
+
+		counts, err := parseNarrativePool(args[0])
+		if err != nil {
+			log.Fatal("Invalid pool: ", err)
+		}
+		name := narrativePoolName(counts)
+
+		if showOdds {
+			printNarrativeOdds(name, counts)
+			return
+		}
+
+		if verbose {
+			printProvenance()
+		}
+		var total NarrativeFace
+		rolled := make([][]NarrativeFace, len(narrativeDice))
+		for i, die := range narrativeDice {
+			for j := 0; j < counts[i]; j++ {
+				draw := rng.Intn(len(die.Faces))
+				face := die.Faces[draw]
+				if verbose {
+					fmt.Printf("Raw draw: %d of [0, %d), %s face %d\n", draw, len(die.Faces), die.Name, draw+1)
+				}
+				rolled[i] = append(rolled[i], face)
+				total = total.add(face)
+			}
+		}
+
+		outcome := "Failure"
+		if total.Success > 0 {
+			outcome = "Success"
+		}
+
+		if quiet {
+			fmt.Println(strings.ToLower(outcome) + " " + total.String())
+			return
+		}
+
+		if a11y {
+			fmt.Printf("Rolling %s. Result: %s, %s.\n", name, strings.ToLower(outcome), total)
+			for i, die := range narrativeDice {
+				for _, face := range rolled[i] {
+					fmt.Printf("%s die: %s.\n", strings.ToUpper(die.Name[:1])+die.Name[1:], face.symbols())
+				}
+			}
+			return
+		}
+
+		fmt.Printf("\n🎲 Rolling %s...\n", name)
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for i, die := range narrativeDice {
+			for _, face := range rolled[i] {
+				fmt.Fprintf(w, "  %s\t%s\n", die.Name, face.symbols())
+			}
+		}
+		w.Flush()
+		fmt.Println()
+		if total.Success > 0 {
+			fmt.Print("✅ ")
+		} else {
+			fmt.Print("❌ ")
+		}
+		fmt.Printf("%s: %s\n", outcome, total)
+		if total.Triumph > 0 {
+			fmt.Printf("✨ Triumph x%d\n", total.Triumph)
+		}
+		if total.Despair > 0 {
+			fmt.Printf("⚡ Despair x%d\n", total.Despair)
+		}
+	},
+}
+
+func init() {
+	narrativeCmd.Flags().Bool("odds", false, "Show the exact chances of success, advantage, triumph and despair instead of rolling")
+}
+
+// parseNarrativePool reads counts of each die from notation like 2a1p2d
+func parseNarrativePool(s string) ([]int, error) {
+	counts := make([]int, len(narrativeDice))
+	total := 0
+	for i := 0; i < len(s); {
+		if s[i] == '+' || unicode.IsSpace(rune(s[i])) {
+			i++
+			continue
+		}
+		start := i
+		for i < len(s) && unicode.IsDigit(rune(s[i])) {
+			i++
+		}
+		n := 1
+		if i > start {
+			n, _ = strconv.Atoi(s[start:i])
+		}
+		if i == len(s) {
+			return nil, fmt.Errorf("%q needs a die letter after it", s[start:])
+		}
+
+		found := false
+		for j, die := range narrativeDice {
+			if unicode.ToLower(rune(s[i])) == rune(die.Letter) {
+				counts[j] += n
+				found = true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown die %q; use p, a, b, c, d or s", s[i])
+		}
+		total += n
+		i++
+	}
+	if total == 0 {
+		return nil, fmt.Errorf("the pool has no dice")
+	}
+	if total > maxDice {
+		return nil, fmt.Errorf("a pool can have at most %d dice", maxDice)
+	}
+	return counts, nil
+}
+
+// narrativePoolName describes a pool, e.g. "2 ability, 1 difficulty"
+func narrativePoolName(counts []int) string {
+	var parts []string
+	for i, die := range narrativeDice {
+		if counts[i] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[i], die.Name))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+func (f NarrativeFace) add(o NarrativeFace) NarrativeFace {
+	return NarrativeFace{f.Success + o.Success, f.Advantage + o.Advantage, f.Triumph + o.Triumph, f.Despair + o.Despair}
+}
+
+// String describes net symbols, e.g. "2 successes, 1 threat"
+func (f NarrativeFace) String() string {
+	var parts []string
+	switch {
+	case f.Success > 0:
+		parts = append(parts, plural(f.Success, "success", "successes"))
+	case f.Success < 0:
+		parts = append(parts, plural(-f.Success, "failure", "failures"))
+	}
+	switch {
+	case f.Advantage > 0:
+		parts = append(parts, plural(f.Advantage, "advantage", "advantages"))
+	case f.Advantage < 0:
+		parts = append(parts, plural(-f.Advantage, "threat", "threats"))
+	}
+	if len(parts) == 0 {
+		return "no net symbols"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// symbols lists the symbols on a single face, e.g. "success, advantage"
+func (f NarrativeFace) symbols() string {
+	var parts []string
+	success := f.Success - f.Triumph + f.Despair
+	for i := 0; i < success; i++ {
+		parts = append(parts, "success")
+	}
+	for i := 0; i > success; i-- {
+		parts = append(parts, "failure")
+	}
+	for i := 0; i < f.Advantage; i++ {
+		parts = append(parts, "advantage")
+	}
+	for i := 0; i > f.Advantage; i-- {
+		parts = append(parts, "threat")
+	}
+	if f.Triumph > 0 {
+		parts = append(parts, "triumph")
+	}
+	if f.Despair > 0 {
+		parts = append(parts, "despair")
+	}
+	if len(parts) == 0 {
+		return "blank"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func plural(n int, one, many string) string {
+	if n == 1 {
+		return "1 " + one
+	}
+	return fmt.Sprintf("%d %s", n, many)
+}
+
+// printNarrativeOdds works out the exact chances for a pool by convolving
+// the net successes and advantages of every die
+func printNarrativeOdds(name string, counts []int) {
+	type net struct{ success, advantage int }
+	dist := map[net]float64{{}: 1}
+	noTriumph, noDespair := 1.0, 1.0
+	for i, die := range narrativeDice {
+		faces := map[net]float64{}
+		triumphs, despairs := 0, 0
+		for _, face := range die.Faces {
+			faces[net{face.Success, face.Advantage}] += 1 / float64(len(die.Faces))
+			triumphs += face.Triumph
+			despairs += face.Despair
+		}
+		for j := 0; j < counts[i]; j++ {
+			next := map[net]float64{}
+			for a, pa := range dist {
+				for b, pb := range faces {
+					next[net{a.success + b.success, a.advantage + b.advantage}] += pa * pb
+				}
+			}
+			dist = next
+			noTriumph *= 1 - float64(triumphs)/float64(len(die.Faces))
+			noDespair *= 1 - float64(despairs)/float64(len(die.Faces))
+		}
+	}
+
+	var success, advantage, threat float64
+	for n, p := range dist {
+		if n.success > 0 {
+			success += p
+		}
+		if n.advantage > 0 {
+			advantage += p
+		}
+		if n.advantage < 0 {
+			threat += p
+		}
+	}
+
+	if quiet {
+		fmt.Println(formatPercent(success))
+		return
+	}
+	if a11y {
+		fmt.Printf("Odds for %s: success %s, net advantage %s, net threat %s, triumph %s, despair %s.\n", name,
+			formatPercent(success), formatPercent(advantage), formatPercent(threat),
+			formatPercent(1-noTriumph), formatPercent(1-noDespair))
+		return
+	}
+	fmt.Printf("Odds for %s:\n", name)
+	fmt.Printf("  Success: %s\n", formatPercent(success))
+	fmt.Printf("  Net advantage: %s\n", formatPercent(advantage))
+	fmt.Printf("  Net threat: %s\n", formatPercent(threat))
+	fmt.Printf("  Triumph: %s\n", formatPercent(1-noTriumph))
+	fmt.Printf("  Despair: %s\n", formatPercent(1-noDespair))
+}
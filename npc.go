@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// npcParts are the tables an NPC is composed from, in the order printed
+var npcParts = []struct {
+	Label string
+	Table string
+}{
+	{"Name", "name"},
+	{"Trait", "trait"},
+	{"Motivation", "motivation"},
+	{"Quirk", "quirk"},
+}
+
+var npcCmd = &cobra.Command{
+	Use:   "npc",
+	Short: "Generate quick NPCs from name, trait, motivation and quirk tables",
+	Long: `Generate an NPC by drawing one entry from each of the name, trait,
+motivation and quirk tables:
+
+  roll npc
+  roll npc -n 3
+
+The tables can be replaced like any other table: put a name.txt, trait.txt,
+motivation.txt or quirk.txt in the tables directory (see spark tables).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		count, _ := cmd.Flags().GetInt("count")
+		if count < 1 || count > maxRepetitions {
+			log.Fatalf("Count must be between 1 and %d", maxRepetitions)
+		}
+
+		tables := make([][]string, len(npcParts))
+		for i, part := range npcParts {
+			entries, err := loadTable(part.Table)
+			if err != nil {
+				log.Fatal("Failed to load table:", err)
+			}
+			tables[i] = entries
+		}
+
+		for n := 0; n < count; n++ {
+			draws := make([]string, len(npcParts))
+			for i, entries := range tables {
+				draws[i] = entries[rng.Intn(len(entries))]
+			}
+
+			switch {
+			case quiet:
+				fmt.Println(strings.Join(draws, "; "))
+			case a11y:
+				if n > 0 {
+					fmt.Println()
+				}
+				for i, part := range npcParts {
+					fmt.Printf("%s: %s.\n", part.Label, draws[i])
+				}
+			default:
+				fmt.Printf("\n👤 %s\n", draws[0])
+				for i, part := range npcParts[1:] {
+					fmt.Printf("  %s: %s\n", part.Label, draws[i+1])
+				}
+			}
+		}
+	},
+}
+
+func init() {
+	npcCmd.Flags().IntP("count", "n", 1, "Number of NPCs to generate")
+}
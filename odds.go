@@ -0,0 +1,345 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// maxOddsRolls bounds how much of the geometric tail Odds stores. Tiny
+// chances would otherwise need millions of entries to cover 99.9%.
+const maxOddsRolls = 1000
+
+// oddsCacheVersion is bumped when the cached Odds format changes
+const oddsCacheVersion = 2
+
+// Odds holds the exact distribution of rolls needed for a success. Rolls
+// covers the pity chain and the start of the geometric tail at the cap; Tail
+// is the chance of each roll in that tail, from which the rest follows.
+type Odds struct {
+	StartPity int       `json:"start_pity"`
+	Expected  float64   `json:"expected"`
+	Never     bool      `json:"never"`
+	Rolls     []float64 `json:"rolls"`
+	Tail      float64   `json:"tail"`
+}
+
+var oddsCmd = &cobra.Command{
+	Use:   "odds [name]",
+	Short: "Show the exact odds of a roll configuration",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		config, err := loadConfig(name)
+		if err != nil {
+			log.Fatal("Failed to load config:", err)
+		}
+
+		pity, _ := cmd.Flags().GetInt("pity")
+		if pity < 0 {
+			state, err := viewState(name)
+			if err != nil {
+				log.Fatal("Failed to load state:", err)
+			}
+			pity = pityLevel(config, state, time.Now())
+		}
+		if pity > config.Pity {
+			pity = config.Pity
+		}
+
+		odds, err := cachedOdds(name, rollChain(config, pity), pity)
+		if err != nil {
+			log.Fatal("Failed to compute odds:", err)
+		}
+
+		if quiet {
+			if odds.Never {
+				fmt.Println("never")
+			} else {
+				fmt.Println(formatFloat(odds.Expected, settings.Precision))
+			}
+			return
+		}
+
+		fmt.Printf("Odds for '%s' from pity %d:\n", name, pity)
+		chance, err := successChance(config, pity)
+		if err != nil {
+			log.Fatal("Failed to compute odds:", err)
+		}
+		fmt.Printf("  Chance next roll: %s\n", formatPercent(chance))
+		if odds.Never {
+			fmt.Printf("  Expected rolls: never succeeds\n")
+			return
+		}
+		fmt.Printf("  Expected rolls: %s\n", formatFloat(odds.Expected, settings.Precision))
+		for _, p := range []float64{0.5, 0.9, 0.99} {
+			fmt.Printf("  %2.0f%% by roll: %s\n", p*100, formatCount(odds.RollsFor(p)))
+		}
+
+		fmt.Println()
+		printDistribution(odds.Rolls)
+		if len(odds.Rolls) == maxOddsRolls {
+			fmt.Printf("\nOnly the first %s rolls are shown.\n", formatCount(maxOddsRolls))
+		}
+	},
+}
+
+func init() {
+	oddsCmd.Flags().Int("pity", -1, "Compute odds from this pity counter instead of the current one")
+	oddsCmd.ValidArgsFunction = completeArgs(configNames)
+}
+
+// varianceChance returns the probability that variance adds the grace bonus
+// to a roll. The roll picks k uniformly from 1..variance and then succeeds
+// with 1/k, which works out to H(variance)/variance.
+func varianceChance(variance int) float64 {
+	if variance <= 0 {
+		return 0
+	}
+	harmonic := 0.0
+	for k := 1; k <= variance; k++ {
+		harmonic += 1 / float64(k)
+	}
+	return harmonic / float64(variance)
+}
+
+// successChance returns the probability that a roll at the given pity succeeds
+func successChance(config *Config, pity int) (float64, error) {
+	bonus := pity * config.Grace
+	q := varianceChance(config.Variance)
+	plain, err := passChance(config, pity, bonus)
+	if err != nil {
+		return 0, err
+	}
+	boosted, err := passChance(config, pity, bonus+config.Grace)
+	if err != nil {
+		return 0, err
+	}
+	return (1-q)*plain + q*boosted, nil
+}
+
+// computeOdds walks the pity chain from the given counter. Every failure
+// moves one pity level up until the cap, after which the chain stays put and
+// the remaining rolls are geometric, so the distribution is cut off once
+// 99.9% of the probability mass has been covered or maxOddsRolls is reached.
+func computeOdds(config *Config, pity int) (*Odds, error) {
+	odds := &Odds{StartPity: pity}
+
+	survive := 1.0
+	expected := 0.0
+	for p := pity; ; p++ {
+		if p > config.Pity {
+			p = config.Pity
+		}
+		s, err := successChance(config, p)
+		if err != nil {
+			return nil, err
+		}
+
+		if p == config.Pity {
+			// Geometric tail at the pity cap
+			if s == 0 {
+				odds.Never = true
+				return odds, nil
+			}
+			odds.Expected = expected + survive/s
+			odds.Tail = s
+			for survive > 0.001 && len(odds.Rolls) < maxOddsRolls {
+				odds.Rolls = append(odds.Rolls, survive*s)
+				survive *= 1 - s
+			}
+			return odds, nil
+		}
+
+		expected += survive
+		odds.Rolls = append(odds.Rolls, survive*s)
+		survive *= 1 - s
+	}
+}
+
+var projectCmd = &cobra.Command{
+	Use:   "project [name]",
+	Short: "Project the chance of success over the next rolls",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		rolls, _ := cmd.Flags().GetInt("rolls")
+		if rolls < 1 {
+			log.Fatal("Rolls must be at least 1")
+		}
+
+		config, err := loadConfig(name)
+		if err != nil {
+			log.Fatal("Failed to load config:", err)
+		}
+
+		state, err := viewState(name)
+		if err != nil {
+			log.Fatal("Failed to load state:", err)
+		}
+		pity := min(pityLevel(config, state, time.Now()), config.Pity)
+
+		first, expected, err := projectRolls(rollChain(config, pity), pity, rolls)
+		if err != nil {
+			log.Fatal("Failed to compute projection:", err)
+		}
+		within := 0.0
+		for _, p := range first {
+			within += p
+		}
+
+		fmt.Printf("Projection for '%s' over the next %d rolls from pity %d:\n", name, rolls, pity)
+		fmt.Printf("  At least one success: %s\n", formatPercent(within))
+		fmt.Printf("  No success at all: %s\n", formatPercent(1-within))
+		fmt.Printf("  Expected successes: %s\n", formatFloat(expected, settings.Precision))
+
+		fmt.Printf("\nFirst success on roll:\n")
+		printDistribution(first)
+	},
+}
+
+func init() {
+	projectCmd.Flags().IntP("rolls", "n", 10, "Number of rolls to project")
+	projectCmd.ValidArgsFunction = completeArgs(configNames)
+}
+
+// projectRolls runs the pity chain forward for n rolls. It returns the
+// probability that the first success lands on each roll, and the expected
+// number of successes when every success resets pity and rolling continues.
+func projectRolls(config *Config, pity, n int) ([]float64, float64, error) {
+	first := make([]float64, n)
+	survive := 1.0
+
+	// Distribution over pity levels for the expected success count
+	dist := make([]float64, config.Pity+1)
+	dist[pity] = 1
+	expected := 0.0
+
+	for i := 0; i < n; i++ {
+		p := min(pity+i, config.Pity)
+		s, err := successChance(config, p)
+		if err != nil {
+			return nil, 0, err
+		}
+		first[i] = survive * s
+		survive *= 1 - s
+
+		next := make([]float64, len(dist))
+		for level, mass := range dist {
+			s, err := successChance(config, level)
+			if err != nil {
+				return nil, 0, err
+			}
+			expected += mass * s
+			next[0] += mass * s
+			next[min(level+1, config.Pity)] += mass * (1 - s)
+		}
+		dist = next
+	}
+
+	return first, expected, nil
+}
+
+// printDistribution prints the chance of the first success landing on each
+// roll along with the running total
+func printDistribution(rolls []float64) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Roll\tChance\tCumulative\t")
+	cumulative := 0.0
+	for i, p := range rolls {
+		cumulative += p
+		fmt.Fprintf(w, "%s\t%s\t%s\t\n", formatCount(i+1), formatPercent(p), formatPercent(cumulative))
+	}
+	w.Flush()
+}
+
+// RollsFor returns the number of rolls needed to reach the given cumulative
+// probability of success
+func (o *Odds) RollsFor(p float64) int {
+	cumulative := 0.0
+	for i, r := range o.Rolls {
+		cumulative += r
+		if cumulative >= p {
+			return i + 1
+		}
+	}
+
+	// Past the stored rolls the tail is geometric: k more rolls leave
+	// survive*(1-Tail)^k without a success
+	survive := 1 - cumulative
+	if o.Tail <= 0 || survive <= 0 {
+		return len(o.Rolls)
+	}
+	if o.Tail >= 1 {
+		return len(o.Rolls) + 1
+	}
+	k := math.Ceil(math.Log((1-p)/survive) / math.Log1p(-o.Tail))
+	return len(o.Rolls) + int(min(max(k, 1), math.MaxInt32))
+}
+
+// oddsCacheEntry holds the odds computed for one version of a config,
+// keyed by starting pity
+type oddsCacheEntry struct {
+	Version int           `json:"version"`
+	Hash    string        `json:"hash"`
+	Odds    map[int]*Odds `json:"odds"`
+}
+
+// configHash identifies a config by its contents so cached odds can be
+// dropped as soon as the TOML file changes
+func configHash(config *Config) string {
+	data, _ := json.Marshal(config)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// cachedOdds returns the odds for a config from the odds bucket, computing
+// and storing them if the config changed since they were cached
+func cachedOdds(name string, config *Config, pity int) (*Odds, error) {
+	hash := configHash(config)
+	var odds *Odds
+	err := db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.CreateBucketIfNotExists([]byte("odds"))
+		if err != nil {
+			return err
+		}
+
+		var entry oddsCacheEntry
+		if data := b.Get([]byte(name)); data != nil {
+			if err := json.Unmarshal(data, &entry); err != nil {
+				return err
+			}
+		}
+		if entry.Hash != hash || entry.Version != oddsCacheVersion {
+			entry = oddsCacheEntry{Version: oddsCacheVersion, Hash: hash, Odds: map[int]*Odds{}}
+		}
+
+		if cached, ok := entry.Odds[pity]; ok {
+			odds = cached
+			return nil
+		}
+
+		odds, err = computeOdds(config, pity)
+		if err != nil {
+			return err
+		}
+		entry.Odds[pity] = odds
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return b.Put([]byte(name), data)
+	})
+	return odds, err
+}
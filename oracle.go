@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// oracleLikelihoods maps each likelihood to its chance of a yes, following
+// the Mythic fate chart at an average chaos factor
+var oracleLikelihoods = []struct {
+	Name   string
+	Chance int
+}{
+	{"certain", 90},
+	{"nearly-certain", 85},
+	{"very-likely", 75},
+	{"likely", 65},
+	{"50-50", 50},
+	{"unlikely", 35},
+	{"very-unlikely", 25},
+	{"nearly-impossible", 15},
+	{"impossible", 10},
+}
+
+// OracleRecord is one question put to the oracle and its answer
+type OracleRecord struct {
+	Question   string    `json:"question"`
+	Likelihood string    `json:"likelihood"`
+	Roll       int       `json:"roll"`
+	Answer     string    `json:"answer"`
+	Event      bool      `json:"event,omitempty"`
+	AskedAt    time.Time `json:"asked_at"`
+}
+
+var oracleCmd = &cobra.Command{
+	Use:   "oracle [question]",
+	Short: "Ask a yes/no question, Mythic style",
+	Long: `Ask a yes/no question and roll d100 against its likelihood.
+
+A roll in the lowest fifth of the yes range is "Yes, and", the top fifth is
+"Yes, but"; the same goes for "No, but" and "No, and" on the other side.
+Doubles (11, 22, ...) at or under the chaos factor also trigger a random
+event. Questions and answers are kept in the oracle history.
+
+Likelihoods: certain, nearly-certain, very-likely, likely, 50-50, unlikely,
+very-unlikely, nearly-impossible, impossible.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		question := args[0]
+		likelihood, _ := cmd.Flags().GetString("likelihood")
+		chaos, _ := cmd.Flags().GetInt("chaos")
+
+		chance, ok := oracleChance(likelihood)
+		if !ok {
+			log.Fatal("Invalid likelihood. Supported: ", strings.Join(oracleLikelihoodNames(), ", "))
+		}
+		if chaos < 1 || chaos > 9 {
+			log.Fatal("Chaos must be between 1 and 9")
+		}
+
+		draw := rng.Intn(100)
+		roll := draw + 1
+		record := OracleRecord{
+			Question:   question,
+			Likelihood: likelihood,
+			Roll:       roll,
+			Answer:     oracleAnswer(roll, chance),
+			Event:      roll%11 == 0 && roll/11 <= chaos,
+			AskedAt:    time.Now().UTC(),
+		}
+		if err := appendHistory("oracle_history", record); err != nil {
+			log.Fatal("Failed to save oracle history:", err)
+		}
+
+		if verbose {
+			printProvenance()
+			fmt.Printf("Raw draw: %d of [0, 100), roll = %d\n", draw, roll)
+		}
+
+		if quiet {
+			fmt.Print(record.Answer)
+			if record.Event {
+				fmt.Print(" (random event)")
+			}
+			fmt.Println()
+			return
+		}
+
+		if a11y {
+			fmt.Printf("Question: %s\n", question)
+			fmt.Printf("Likelihood %s, %d percent. Rolled %d. Answer: %s.\n", likelihood, chance, roll, record.Answer)
+			if record.Event {
+				fmt.Println("A random event is triggered.")
+			}
+			return
+		}
+
+		fmt.Printf("\n🔮 %s (%s, %d%%)\n", question, likelihood, chance)
+		fmt.Printf("Roll: %d\n", roll)
+		if strings.HasPrefix(record.Answer, "Yes") {
+			fmt.Printf("✅ %s\n", record.Answer)
+		} else {
+			fmt.Printf("❌ %s\n", record.Answer)
+		}
+		if record.Event {
+			fmt.Println("⚡ Random event!")
+		}
+	},
+}
+
+var oracleHistoryCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Show recent oracle questions and answers, newest first",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		var records []OracleRecord
+		err := loadHistory("oracle_history", func(v []byte) error {
+			var record OracleRecord
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			records = append(records, record)
+			return nil
+		})
+		if err != nil {
+			log.Fatal("Failed to load oracle history:", err)
+		}
+		if len(records) == 0 {
+			if !quiet {
+				fmt.Println("No oracle questions yet")
+			}
+			return
+		}
+
+		for i := len(records) - 1; i >= 0 && (limit <= 0 || len(records)-i <= limit); i-- {
+			record := records[i]
+			answer := record.Answer
+			if record.Event {
+				answer += " (random event)"
+			}
+			if quiet {
+				fmt.Printf("%s\t%s\n", record.Question, answer)
+				continue
+			}
+			fmt.Printf("%s  %s (%s, rolled %d): %s\n",
+				formatTime(record.AskedAt), record.Question, record.Likelihood, record.Roll, answer)
+		}
+	},
+}
+
+func init() {
+	oracleCmd.Flags().String("likelihood", "50-50", "How likely a yes is, e.g. likely or unlikely")
+	oracleCmd.Flags().Int("chaos", 5, "Chaos factor (1-9); higher makes random events more common")
+	oracleCmd.AddCommand(oracleHistoryCmd)
+	oracleHistoryCmd.Flags().IntP("limit", "n", 10, "Number of questions to show (0 for all)")
+}
+
+func oracleChance(likelihood string) (int, bool) {
+	for _, l := range oracleLikelihoods {
+		if l.Name == likelihood {
+			return l.Chance, true
+		}
+	}
+	return 0, false
+}
+
+func oracleLikelihoodNames() []string {
+	names := make([]string, len(oracleLikelihoods))
+	for i, l := range oracleLikelihoods {
+		names[i] = l.Name
+	}
+	return names
+}
+
+// oracleAnswer grades a d100 roll against the chance of a yes. The outer
+// fifth of each side is emphatic ("and"), the fifth nearest the threshold
+// is qualified ("but").
+func oracleAnswer(roll, chance int) string {
+	yesBand, noBand := chance/5, (100-chance)/5
+	switch {
+	case roll <= yesBand:
+		return "Yes, and"
+	case roll <= chance-yesBand:
+		return "Yes"
+	case roll <= chance:
+		return "Yes, but"
+	case roll <= chance+noBand:
+		return "No, but"
+	case roll <= 100-noBand:
+		return "No"
+	}
+	return "No, and"
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var pickCmd = &cobra.Command{
+	Use:   "pick [item...]",
+	Short: "Pick one or more items from a list, optionally weighted",
+	Long: `Pick from items given as arguments, in a file (one per line, with # for
+comments), or on stdin:
+
+  roll pick pizza sushi tacos
+  roll pick --file lunch.txt -n 2
+  roll pick --weighted pizza:3 sushi:1 tacos
+
+With --weighted, an item written name:weight is picked in proportion to its
+weight; items without one weigh 1. Several picks (-n) never repeat an item.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		file, _ := cmd.Flags().GetString("file")
+		n, _ := cmd.Flags().GetInt("count")
+		weighted, _ := cmd.Flags().GetBool("weighted")
+
+		items := args
+		var err error
+		switch {
+		case file != "" && len(args) > 0:
+			log.Fatal("Give items as arguments or with --file, not both")
+		case file != "":
+			items, err = readLines(file)
+		case len(args) == 0:
+			items, err = scanLines(os.Stdin)
+		}
+		if err != nil {
+			log.Fatal("Failed to read items:", err)
+		}
+
+		weights := make([]float64, len(items))
+		for i, item := range items {
+			weights[i] = 1
+			if !weighted {
+				continue
+			}
+			name, weightText, hasWeight := cutLast(item, ":")
+			if !hasWeight {
+				continue
+			}
+			w, err := strconv.ParseFloat(strings.TrimSpace(weightText), 64)
+			if err != nil || w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+				log.Fatalf("Weight for '%s' must be a non-negative number", name)
+			}
+			items[i], weights[i] = strings.TrimSpace(name), w
+		}
+
+		if len(items) == 0 {
+			log.Fatal("Nothing to pick from")
+		}
+		if n < 1 {
+			log.Fatal("-n must be at least 1")
+		}
+		chances := normalise(weights)
+		if chances == nil {
+			log.Fatal("Every item weighs 0, so there is nothing to pick")
+		}
+		available := 0
+		for _, w := range weights {
+			if w > 0 {
+				available++
+			}
+		}
+		if n > available {
+			log.Fatalf("Cannot pick %d different items from %d", n, available)
+		}
+
+		if verbose {
+			printProvenance()
+		}
+
+		// Each pick is removed before the next, so picks never repeat
+		picked := make([]int, n)
+		remaining := append([]float64(nil), weights...)
+		for i := range picked {
+			picked[i] = pickWeighted(normalise(remaining))
+			remaining[picked[i]] = 0
+		}
+
+		if quiet {
+			for _, i := range picked {
+				fmt.Println(items[i])
+			}
+			return
+		}
+
+		if a11y {
+			names := make([]string, n)
+			for j, i := range picked {
+				names[j] = items[i]
+			}
+			fmt.Printf("Picked %s from %d items.\n", strings.Join(names, ", "), len(items))
+			if n == 1 {
+				fmt.Printf("It had a %s chance.\n", formatPercent(chances[picked[0]]))
+			}
+			return
+		}
+
+		fmt.Printf("\n🎲 Picking %d of %d items...\n", n, len(items))
+		for _, i := range picked {
+			// With several picks the share of the weight is not the chance of
+			// being picked, so say which it is
+			if n > 1 {
+				fmt.Printf("  %s (%s of the weight)\n", items[i], formatPercent(chances[i]))
+				continue
+			}
+			fmt.Printf("  %s (%s)\n", items[i], formatPercent(chances[i]))
+		}
+	},
+}
+
+func init() {
+	pickCmd.Flags().String("file", "", "Read items from this file, one per line")
+	pickCmd.Flags().IntP("count", "n", 1, "Number of different items to pick")
+	pickCmd.Flags().Bool("weighted", false, "Read name:weight items and pick in proportion to weight")
+}
+
+// cutLast splits s around the last sep, so "a:b:3" gives "a:b" and "3"
+func cutLast(s, sep string) (before, after string, found bool) {
+	if i := strings.LastIndex(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Pool counts successes instead of summing, as in World of Darkness: each
+// face of Target or more is a success, faces of Double or more (if set)
+// count twice, and with Botch every 1 cancels a success. A pool that rolls
+// no successes but at least one 1 botches. With Glitch, as in Shadowrun, a
+// pool where more than half the dice show 1 glitches, and a glitch with no
+// successes is critical.
+type Pool struct {
+	Target int  `json:"target"`
+	Double int  `json:"double,omitempty"`
+	Botch  bool `json:"botch,omitempty"`
+	Glitch bool `json:"glitch,omitempty"`
+}
+
+// PoolResult is what a pool roll came to
+type PoolResult struct {
+	Successes      int
+	Ones           int
+	Rolls          int
+	Net            int
+	Botched        bool
+	Glitched       bool
+	CriticalGlitch bool
+}
+
+// check reports why an expression cannot be rolled as this pool
+func (p Pool) check(expr *DiceExpr) error {
+	for _, term := range expr.Terms {
+		switch {
+		case term.Sides == 0 || term.Sign < 0:
+			return fmt.Errorf("a dice pool can only add dice, not numbers or subtracted dice")
+		case term.Fudge || term.Digits > 0 || term.Percentile:
+			return fmt.Errorf("fudge, digit and percentile dice cannot be rolled as a pool")
+		case term.Select != "":
+			return fmt.Errorf("keep and drop rules do not apply to a dice pool")
+		case p.Target < 1 || p.Target > term.Sides:
+			return fmt.Errorf("target must be between 1 and %d for %s", term.Sides, term)
+		}
+	}
+	if p.Double < 0 {
+		return fmt.Errorf("double must be non-negative")
+	}
+	return nil
+}
+
+// score returns the successes a single face is worth
+func (p Pool) score(face int) int {
+	switch {
+	case p.Double > 0 && face >= p.Double && face >= p.Target:
+		return 2
+	case face >= p.Target:
+		return 1
+	}
+	return 0
+}
+
+// Count tallies a roll. Every face counts as its own die, so an exploding
+// die (10-again) adds its extra rolls to the pool.
+func (p Pool) Count(roll DiceRoll) PoolResult {
+	var result PoolResult
+	for _, dice := range roll.Dice {
+		for _, die := range dice {
+			for _, face := range die.Faces {
+				result.Successes += p.score(face)
+				result.Rolls++
+				if face == 1 {
+					result.Ones++
+				}
+			}
+		}
+	}
+	result.Net, result.Botched = p.net(result.Successes, result.Ones)
+	result.Glitched, result.CriticalGlitch = p.glitch(result.Successes, result.Ones, result.Rolls)
+	return result
+}
+
+// net applies 1s to the successes when botches are on
+func (p Pool) net(successes, ones int) (int, bool) {
+	if !p.Botch {
+		return successes, false
+	}
+	return max(successes-ones, 0), successes == 0 && ones > 0
+}
+
+// glitch reports whether more than half the dice rolled show 1, and whether
+// that glitch is critical because nothing succeeded
+func (p Pool) glitch(successes, ones, rolls int) (bool, bool) {
+	if !p.Glitch || ones*2 <= rolls {
+		return false, false
+	}
+	return true, successes == 0
+}
+
+// poolTally is a count of successes, 1s and dice rolled, the state pool
+// odds track
+type poolTally struct{ successes, ones, rolls int }
+
+// add combines two tallies
+func (t poolTally) add(o poolTally) poolTally {
+	return poolTally{t.successes + o.successes, t.ones + o.ones, t.rolls + o.rolls}
+}
+
+// PoolOdds is the exact distribution of net successes, with the chances of
+// the pool's failure modes
+type PoolOdds struct {
+	Successes      Distribution
+	Botch          float64
+	Glitch         float64
+	CriticalGlitch float64
+}
+
+// Distribution works out the exact odds of a pool
+func (p Pool) Distribution(expr *DiceExpr) (PoolOdds, error) {
+	work := 0.0
+	for _, term := range expr.Terms {
+		_, hi := term.bounds(expr.ExplodeCap)
+		rolls := float64(hi) / float64(term.Sides) * float64(term.Count)
+		work += float64(term.Count) * rolls * rolls * rolls
+	}
+	if work > maxConvolution {
+		return PoolOdds{}, fmt.Errorf("%s has too many outcomes to work out exactly", expr)
+	}
+
+	tallies := map[poolTally]float64{{}: 1}
+	for _, term := range expr.Terms {
+		die := p.dieTallies(term, expr.ExplodeCap)
+		for i := 0; i < term.Count; i++ {
+			next := map[poolTally]float64{}
+			for a, pa := range tallies {
+				for b, pb := range die {
+					next[a.add(b)] += pa * pb
+				}
+			}
+			tallies = next
+		}
+	}
+
+	odds := PoolOdds{Successes: Distribution{}}
+	for t, prob := range tallies {
+		net, botch := p.net(t.successes, t.ones)
+		odds.Successes[net] += prob
+		if botch {
+			odds.Botch += prob
+		}
+		glitch, critical := p.glitch(t.successes, t.ones, t.rolls)
+		if glitch {
+			odds.Glitch += prob
+		}
+		if critical {
+			odds.CriticalGlitch += prob
+		}
+	}
+	return odds, nil
+}
+
+// dieTallies returns the distribution of one die's tally, following the
+// explosion chain when the first face is the top face
+func (p Pool) dieTallies(term DiceTerm, explodeCap int) map[poolTally]float64 {
+	tally := func(face int) poolTally {
+		t := poolTally{successes: p.score(face), rolls: 1}
+		if face == 1 {
+			t.ones = 1
+		}
+		return t
+	}
+
+	// chain[k] is a fresh exploding die with k explosions left
+	chain := make([]map[poolTally]float64, explodeCap)
+	for k := range chain {
+		chain[k] = map[poolTally]float64{}
+		for face := 1; face <= term.Sides; face++ {
+			t := tally(face)
+			if face < term.Sides || k == 0 {
+				chain[k][t] += 1 / float64(term.Sides)
+				continue
+			}
+			for rest, q := range chain[k-1] {
+				chain[k][t.add(rest)] += q / float64(term.Sides)
+			}
+		}
+	}
+
+	die := map[poolTally]float64{}
+	for face, prob := range term.faceDistribution() {
+		t := tally(face)
+		if !term.Explode || face != term.Sides || explodeCap == 0 {
+			die[t] += prob
+			continue
+		}
+		for rest, q := range chain[explodeCap-1] {
+			die[t.add(rest)] += prob * q
+		}
+	}
+	return die
+}
+
+// String describes the pool's rules, e.g. "against 8, 10+ count double"
+func (p Pool) String() string {
+	parts := []string{fmt.Sprintf("against %d", p.Target)}
+	if p.Double > 0 {
+		parts = append(parts, fmt.Sprintf("%d+ count double", p.Double))
+	}
+	if p.Botch {
+		parts = append(parts, "1s cancel")
+	}
+	if p.Glitch {
+		parts = append(parts, "glitches on over half 1s")
+	}
+	return strings.Join(parts, ", ")
+}
+
+// noun names what the pool counts: hits for glitch pools, after Shadowrun
+func (p Pool) noun() string {
+	if p.Glitch {
+		return "Hits"
+	}
+	return "Successes"
+}
+
+// printPoolRoll prints the dice of a pool roll and the successes they score
+func printPoolRoll(name string, roll DiceRoll, pool Pool) {
+	result := pool.Count(roll)
+	if verbose {
+		printProvenance()
+		for i, term := range roll.Expr.Terms {
+			for _, die := range roll.Dice[i] {
+				for _, face := range append(append([]int(nil), die.Rerolled...), die.Faces...) {
+					fmt.Printf("Raw draw: %d of [0, %d), roll = %d\n", face-1, term.Sides, face)
+				}
+			}
+		}
+	}
+
+	if quiet {
+		switch {
+		case result.Botched:
+			fmt.Println("botch")
+		case result.CriticalGlitch:
+			fmt.Println("critical glitch")
+		case result.Glitched:
+			fmt.Printf("%d glitch\n", result.Net)
+		default:
+			fmt.Println(result.Net)
+		}
+		return
+	}
+
+	if a11y {
+		fmt.Printf("Rolling %s %s. ", name, pool)
+		switch {
+		case result.Botched:
+			fmt.Println("Botch: no successes and at least one 1.")
+		case result.CriticalGlitch:
+			fmt.Printf("Critical glitch: no hits and %d of %d dice showed 1.\n", result.Ones, result.Rolls)
+		default:
+			fmt.Printf("%s: %d.\n", pool.noun(), result.Net)
+		}
+		fmt.Printf("The dice showed %s.\n", listAnd(poolFaces(roll)))
+		if pool.Botch && result.Ones > 0 && !result.Botched {
+			fmt.Printf("%d successes rolled, %d cancelled by 1s.\n", result.Successes, result.Successes-result.Net)
+		}
+		if result.Glitched && !result.CriticalGlitch {
+			fmt.Printf("Glitch: %d of %d dice showed 1.\n", result.Ones, result.Rolls)
+		}
+		return
+	}
+
+	fmt.Printf("\n🎲 Rolling %s %s...\n", name, pool)
+	fmt.Printf("Dice: %s\n", roll.Detail())
+	if pool.Botch && result.Ones > 0 && !result.Botched {
+		fmt.Printf("Rolled %d successes, %d cancelled by 1s\n", result.Successes, result.Successes-result.Net)
+	}
+	switch {
+	case result.Botched:
+		fmt.Println("❌ Botch! No successes and at least one 1")
+	case result.CriticalGlitch:
+		fmt.Printf("❌ Critical glitch! No hits and %d of %d dice showed 1\n", result.Ones, result.Rolls)
+	case result.Net == 0:
+		fmt.Printf("❌ No %s\n", strings.ToLower(pool.noun()))
+	default:
+		fmt.Printf("✅ %s: %d\n", pool.noun(), result.Net)
+	}
+	if result.Glitched && !result.CriticalGlitch {
+		fmt.Printf("⚡ Glitch! %d of %d dice showed 1\n", result.Ones, result.Rolls)
+	}
+}
+
+// poolFaces lists every face in a roll, extra explosion rolls included
+func poolFaces(roll DiceRoll) []int {
+	var faces []int
+	for _, dice := range roll.Dice {
+		for _, die := range dice {
+			faces = append(faces, die.Faces...)
+		}
+	}
+	return faces
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var practiceCmd = &cobra.Command{
+	Use:   "practice [name]",
+	Short: "Roll a throwaway copy of a configuration's current state",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		rolls, _ := cmd.Flags().GetInt("rolls")
+
+		config, err := loadConfig(name)
+		if err != nil {
+			log.Fatal("Failed to load config:", err)
+		}
+
+		state, err := viewState(name)
+		if err != nil {
+			log.Fatal("Failed to load state:", err)
+		}
+
+		// Work on a copy so the real pity counter is never touched
+		practice := *state
+		fmt.Printf("Practicing '%s' from pity %d. Nothing here is saved.\n", name, practice.PityCounter)
+
+		successes, count := 0, 0
+		if rolls > 0 {
+			fmt.Println()
+			for i := 1; i <= rolls; i++ {
+				result := rollConfig(config, &practice)
+				outcome := "fail"
+				if result.Success {
+					outcome = "SUCCESS"
+					successes++
+				}
+				fmt.Printf("%4d. pity %d, chance %3d%%, rolled %3d, %s\n",
+					i, result.Pity, result.EffectiveChance, result.Roll, outcome)
+			}
+			count = rolls
+		} else {
+			in := bufio.NewReader(os.Stdin)
+			for waitForEnter(in, "\nPress Enter to roll, or type q and Enter to stop: ") {
+				result := rollConfig(config, &practice)
+				printRoll(name, config, result)
+				if result.Success {
+					successes++
+				}
+				count++
+			}
+		}
+
+		fmt.Printf("\n%s, %s\n", plural(count, "practice roll", "practice rolls"), plural(successes, "success", "successes"))
+		fmt.Printf("Practice pity ended at %d; real pity is still %d\n", practice.PityCounter, state.PityCounter)
+	},
+}
+
+func init() {
+	practiceCmd.Flags().IntP("rolls", "n", 0, "Roll this many times without prompting")
+	practiceCmd.ValidArgsFunction = completeArgs(configNames)
+}
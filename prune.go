@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// deleteConfig moves a config, its state and versions to the trash, then
+// deletes them along with its cached odds
+func deleteConfig(name string) error {
+	configPath := filepath.Join(configDir, name+".toml")
+	data, err := os.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+	versions, err := loadConfigVersions(name)
+	if err != nil {
+		return err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		item := TrashItem{Kind: "config", Name: name, Data: string(data), Versions: versions}
+		if b := tx.Bucket([]byte("states")); b != nil {
+			if state := b.Get([]byte(name)); state != nil {
+				item.State = append(json.RawMessage(nil), state...)
+			}
+		}
+		if err := moveToTrash(tx, item); err != nil {
+			return err
+		}
+
+		for _, bucket := range []string{"states", "odds"} {
+			b := tx.Bucket([]byte(bucket))
+			if b == nil {
+				continue
+			}
+			if err := b.Delete([]byte(name)); err != nil {
+				return err
+			}
+		}
+		if b := tx.Bucket([]byte("versions")); b != nil && b.Bucket([]byte(name)) != nil {
+			return b.DeleteBucket([]byte(name))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return os.Remove(configPath)
+}
+
+// matchingConfigs returns the configs with the tag (if given) that have not
+// been used for at least age (if not 0). A config is used when it is rolled,
+// or when its file changes if it has never been rolled.
+func matchingConfigs(tag string, age time.Duration) ([]string, error) {
+	var names []string
+	for _, name := range configNames() {
+		config, err := loadConfig(name)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		if tag != "" && !hasTag(config, tag) {
+			continue
+		}
+		if age > 0 {
+			used, err := lastUsed(name)
+			if err != nil {
+				return nil, err
+			}
+			if time.Since(used) < age {
+				continue
+			}
+		}
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func hasTag(config *Config, tag string) bool {
+	for _, t := range config.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// lastUsed returns when a config was last rolled, falling back to when its
+// file was last changed
+func lastUsed(name string) (time.Time, error) {
+	state, err := loadState(name)
+	if err == nil && !state.LastRolledAt.IsZero() {
+		return state.LastRolledAt, nil
+	}
+	info, err := os.Stat(filepath.Join(configDir, name+".toml"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// parseAge reads an age in days (180d), weeks (26w) or a Go duration (72h).
+// An empty age is 0, meaning no limit.
+func parseAge(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, err := strconv.Atoi(strings.TrimSuffix(s, suffix)); err == nil && strings.HasSuffix(s, suffix) {
+			if n <= 0 {
+				break
+			}
+			return time.Duration(n) * unit, nil
+		}
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("%q must be a positive age like 180d, 26w or 72h", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// maxRandAttempts bounds how many draws rand makes for one number before
+// deciding the range is too unlikely under the distribution
+const maxRandAttempts = 10000
+
+var randCmd = &cobra.Command{
+	Use:   "rand",
+	Short: "Draw random numbers from a uniform, normal, exponential or zipf distribution",
+	Long: `Draw --count numbers between --min and --max, inclusive:
+
+  roll rand --min 1 --max 1000 --count 10 --dist normal --mean 500 --stddev 100
+
+uniform      every number is equally likely (the default)
+normal       centred on --mean with spread --stddev
+exponential  --mean above --min on average, falling off towards --max
+zipf         --min is most likely, with weight falling off as rank^-s (--s > 1)
+
+Draws outside the range are redrawn. Numbers are whole unless --float is
+given (zipf is always whole). --seed repeats a draw exactly.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		lo, _ := cmd.Flags().GetFloat64("min")
+		hi, _ := cmd.Flags().GetFloat64("max")
+		count, _ := cmd.Flags().GetInt("count")
+		dist, _ := cmd.Flags().GetString("dist")
+		mean, _ := cmd.Flags().GetFloat64("mean")
+		stddev, _ := cmd.Flags().GetFloat64("stddev")
+		s, _ := cmd.Flags().GetFloat64("s")
+		float, _ := cmd.Flags().GetBool("float")
+
+		if count < 1 {
+			log.Fatal("Count must be at least 1")
+		}
+		if hi < lo {
+			log.Fatal("Max must not be below min")
+		}
+		if !float && math.Ceil(lo) > math.Floor(hi) {
+			log.Fatal("There are no whole numbers between min and max; use --float")
+		}
+		if first, last := math.Ceil(lo), math.Floor(hi); (!float || dist == "zipf") &&
+			(first < math.MinInt64 || last >= math.MaxInt64 || last-first >= math.MaxInt64) {
+			log.Fatal("The range is too large to draw whole numbers from; use --float")
+		}
+		if !cmd.Flags().Changed("mean") {
+			mean = (lo + hi) / 2
+		}
+		if !cmd.Flags().Changed("stddev") {
+			stddev = (hi - lo) / 6
+		}
+
+		var draw func() float64
+		switch dist {
+		case "uniform":
+			if float {
+				draw = func() float64 { return lo + rng.Float64()*(hi-lo) }
+			} else {
+				first, last := int64(math.Ceil(lo)), int64(math.Floor(hi))
+				draw = func() float64 { return float64(first + rng.Int63n(last-first+1)) }
+			}
+		case "normal":
+			if stddev <= 0 {
+				log.Fatal("Stddev must be positive")
+			}
+			draw = func() float64 { return mean + rng.NormFloat64()*stddev }
+		case "exponential":
+			if mean <= lo {
+				log.Fatal("Mean must be above min for an exponential distribution")
+			}
+			draw = func() float64 { return lo + rng.ExpFloat64()*(mean-lo) }
+		case "zipf":
+			if s <= 1 {
+				log.Fatal("--s must be greater than 1 for zipf")
+			}
+			float = false
+			first, last := math.Ceil(lo), math.Floor(hi)
+			zipf := rand.NewZipf(rng, s, 1, uint64(last-first))
+			draw = func() float64 { return first + float64(zipf.Uint64()) }
+		default:
+			log.Fatalf("Unknown distribution '%s'; use uniform, normal, exponential or zipf", dist)
+		}
+
+		numbers := make([]float64, count)
+		for i := range numbers {
+			n, err := drawInRange(draw, lo, hi, float)
+			if err != nil {
+				log.Fatal(err)
+			}
+			numbers[i] = n
+		}
+
+		if verbose {
+			printProvenance()
+		}
+
+		formatted := make([]string, count)
+		for i, n := range numbers {
+			if float {
+				formatted[i] = strconv.FormatFloat(n, 'f', settings.Precision, 64)
+			} else {
+				formatted[i] = strconv.FormatFloat(n, 'f', 0, 64)
+			}
+		}
+
+		switch {
+		case quiet:
+			fmt.Println(strings.Join(formatted, "\n"))
+		case a11y:
+			fmt.Printf("Drew %d %s numbers between %s and %s: %s.\n", count, dist,
+				strconv.FormatFloat(lo, 'f', -1, 64), strconv.FormatFloat(hi, 'f', -1, 64), strings.Join(formatted, ", "))
+		default:
+			fmt.Printf("\n🎲 %d %s numbers in [%s, %s]:\n", count, dist,
+				strconv.FormatFloat(lo, 'f', -1, 64), strconv.FormatFloat(hi, 'f', -1, 64))
+			fmt.Println(strings.Join(formatted, "\n"))
+			fmt.Printf("\nSeed: %d (use --seed to repeat this draw)\n", rngSeed)
+		}
+	},
+}
+
+func init() {
+	randCmd.Flags().Float64("min", 1, "Smallest number to draw")
+	randCmd.Flags().Float64("max", 100, "Largest number to draw")
+	randCmd.Flags().IntP("count", "n", 1, "How many numbers to draw")
+	randCmd.Flags().String("dist", "uniform", "Distribution: uniform, normal, exponential or zipf")
+	randCmd.Flags().Float64("mean", 0, "Mean for normal and exponential (default the middle of the range)")
+	randCmd.Flags().Float64("stddev", 0, "Standard deviation for normal (default a sixth of the range)")
+	randCmd.Flags().Float64("s", 1.1, "Exponent for zipf, above 1; larger favours --min more")
+	randCmd.Flags().Bool("float", false, "Draw real numbers instead of whole ones")
+}
+
+// drawInRange draws until a number lands in [lo, hi], rounding to whole
+// numbers first unless float is set
+func drawInRange(draw func() float64, lo, hi float64, float bool) (float64, error) {
+	for attempt := 0; attempt < maxRandAttempts; attempt++ {
+		n := draw()
+		if !float {
+			n = math.Round(n)
+		}
+		if n >= lo && n <= hi {
+			return n, nil
+		}
+	}
+	return 0, fmt.Errorf("no draw landed between min and max after %d tries; widen the range or move the mean", maxRandAttempts)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var rangeCmd = &cobra.Command{
+	Use:   "range [min] [max]",
+	Short: "Draw whole numbers uniformly between min and max, inclusive",
+	Long: `Draw whole numbers from min to max, inclusive, each equally likely:
+
+  roll range 1 100
+  roll range 1 49 --count 6 --unique
+  roll range -n 3 -- -10 10
+
+--unique draws without replacement, so no number comes up twice. Put --
+after any flags and before a negative min so it is not read as a flag.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		count, _ := cmd.Flags().GetInt("count")
+		unique, _ := cmd.Flags().GetBool("unique")
+
+		lo, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			log.Fatal("Min must be a whole number")
+		}
+		hi, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			log.Fatal("Max must be a whole number")
+		}
+		if hi < lo {
+			log.Fatal("Max must not be below min")
+		}
+		if count < 1 {
+			log.Fatal("Count must be at least 1")
+		}
+		size := uint64(hi-lo) + 1
+		if size == 0 || size > math.MaxInt64 {
+			log.Fatal("The range is too large to draw from")
+		}
+		if unique && uint64(count) > size {
+			log.Fatalf("Cannot draw %d unique numbers from a range of %s", count, formatCount(int(size)))
+		}
+
+		var numbers []int64
+		if unique {
+			numbers = drawUnique(lo, int64(size), count)
+		} else {
+			numbers = make([]int64, count)
+			for i := range numbers {
+				numbers[i] = lo + rng.Int63n(int64(size))
+			}
+		}
+
+		if verbose {
+			printProvenance()
+		}
+		formatted := make([]string, count)
+		for i, n := range numbers {
+			formatted[i] = strconv.FormatInt(n, 10)
+		}
+
+		switch {
+		case quiet:
+			fmt.Println(strings.Join(formatted, "\n"))
+		case a11y:
+			kind := "numbers"
+			if unique {
+				kind = "unique numbers"
+			}
+			fmt.Printf("Drew %d %s from %d to %d: %s.\n", count, kind, lo, hi, strings.Join(formatted, ", "))
+		default:
+			fmt.Printf("\n🎲 %d from %d to %d", count, lo, hi)
+			if unique {
+				fmt.Print(", no repeats")
+			}
+			fmt.Println(":")
+			fmt.Println(strings.Join(formatted, "\n"))
+		}
+	},
+}
+
+func init() {
+	rangeCmd.Flags().IntP("count", "n", 1, "How many numbers to draw")
+	rangeCmd.Flags().Bool("unique", false, "Never draw the same number twice")
+}
+
+// drawUnique draws count different numbers from lo to lo+size-1 with a
+// partial Fisher-Yates shuffle, tracking only the positions it has swapped
+// so that huge ranges cost no more than small ones
+func drawUnique(lo, size int64, count int) []int64 {
+	swapped := map[int64]int64{}
+	at := func(i int64) int64 {
+		if v, ok := swapped[i]; ok {
+			return v
+		}
+		return i
+	}
+
+	numbers := make([]int64, count)
+	for i := range numbers {
+		j := int64(i) + rng.Int63n(size-int64(i))
+		numbers[i] = lo + at(j)
+		swapped[j] = at(int64(i))
+	}
+	return numbers
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+)
+
+// recordingVersion is bumped when the recording format changes
+const recordingVersion = 1
+
+// Recording is everything needed to replay a roll exactly: the config as it
+// was, the state before the roll, the seed and the time it was rolled at.
+// Result is what came out, so a replay can be checked against it.
+type Recording struct {
+	Version  int        `json:"version"`
+	Name     string     `json:"name"`
+	Config   string     `json:"config"`
+	State    State      `json:"state"`
+	Seed     int64      `json:"seed"`
+	RolledAt time.Time  `json:"rolled_at"`
+	Result   RollResult `json:"result"`
+}
+
+var recordCmd = &cobra.Command{
+	Use:   "record [name]",
+	Short: "Roll a configuration and save a file that replays the roll exactly",
+	Long: `Roll a configuration as roll does, and also save a recording of it:
+
+  roll record gacha -o pull.rollrec
+  roll playback pull.rollrec
+
+The recording holds the config, its state before the roll, the random seed
+and the time, so anyone can replay the same roll and check it came out the
+same. Share it to show off a lucky pull or to report a surprising one.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		output, _ := cmd.Flags().GetString("output")
+		name := args[0]
+		if output == "" {
+			output = name + ".rollrec"
+		}
+
+		config, err := loadConfig(name)
+		if err != nil {
+			log.Fatal("Failed to load config:", err)
+		}
+		data, err := os.ReadFile(filepath.Join(configDir, name+".toml"))
+		if err != nil {
+			log.Fatal("Failed to read config file:", err)
+		}
+		before := State{}
+		if state, err := loadState(name); err == nil {
+			before = *state
+		}
+
+		if err := snapshotConfig(name); err != nil {
+			log.Fatal("Failed to record config version:", err)
+		}
+		result, err := rollAndRecord(name, config)
+		if err != nil {
+			log.Fatal("Failed to update state:", err)
+		}
+		after, err := loadState(name)
+		if err != nil {
+			log.Fatal("Failed to load state:", err)
+		}
+
+		recording := Recording{
+			Version:  recordingVersion,
+			Name:     name,
+			Config:   string(data),
+			State:    before,
+			Seed:     rngSeed,
+			RolledAt: after.LastRolledAt,
+			Result:   result,
+		}
+		out, err := json.MarshalIndent(recording, "", "  ")
+		if err != nil {
+			log.Fatal("Failed to encode recording:", err)
+		}
+		if err := os.WriteFile(output, append(out, '\n'), 0644); err != nil {
+			log.Fatal("Failed to save recording:", err)
+		}
+		if ci {
+			if err := printRollJSON(name, result); err != nil {
+				log.Fatal("Failed to print result:", err)
+			}
+		}
+		if !quiet && !ci {
+			fmt.Printf("\nRecorded to %s (replay with: roll playback %s)\n", output, shellArg(output))
+		}
+	},
+}
+
+var playbackCmd = &cobra.Command{
+	Use:   "playback [file]",
+	Short: "Replay a recorded roll and check it comes out the same",
+	Long: `Replay a roll saved by record. The roll is shown as it was first
+printed and checked against the recorded result; nothing is saved and the
+recorded config does not need to exist here. Exits with 1 if the replay
+does not match, e.g. because the recording was edited.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatal("Failed to read recording:", err)
+		}
+		var recording Recording
+		if err := json.Unmarshal(data, &recording); err != nil {
+			log.Fatal("Invalid recording: ", err)
+		}
+		if recording.Version != recordingVersion {
+			log.Fatalf("Recording version %d is not supported; this roll reads version %d", recording.Version, recordingVersion)
+		}
+
+		var config Config
+		if _, err := toml.Decode(recording.Config, &config); err != nil {
+			log.Fatal("Invalid config in recording: ", err)
+		}
+		if errs := validateConfig(&config); len(errs) > 0 {
+			log.Fatal("Invalid config in recording: ", errs[0])
+		}
+
+		rngSeed = recording.Seed
+		rng = rand.New(rand.NewSource(rngSeed))
+		state := recording.State
+
+		switch {
+		case quiet || ci:
+		case a11y:
+			fmt.Printf("Replaying %s as rolled at %s.\n", recording.Name, formatTime(recording.RolledAt))
+		default:
+			fmt.Printf("▶ Replaying '%s' as rolled at %s\n", recording.Name, formatTime(recording.RolledAt))
+		}
+		result := rollConfigAt(&config, &state, recording.RolledAt)
+		printRoll(recording.Name, &config, result)
+		if ci {
+			if err := printRollJSON(recording.Name, result); err != nil {
+				log.Fatal("Failed to print result:", err)
+			}
+		}
+
+		if !reflect.DeepEqual(result, recording.Result) {
+			log.Fatalf("Replay does not match the recording: rolled %d, recorded %d", result.Roll, recording.Result.Roll)
+		}
+		switch {
+		case quiet:
+		case a11y:
+			fmt.Println("Verified: the replay matches the recording.")
+		default:
+			fmt.Println("\n✅ Verified: the replay matches the recording")
+		}
+	},
+}
+
+func init() {
+	recordCmd.Flags().StringP("output", "o", "", "File to save the recording to (default [name].rollrec)")
+	recordCmd.ValidArgsFunction = completeArgs(configNames)
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// Reward is something earned by winning a config's roll after finishing a
+// task. A reward with no tasks is rolled for after any task.
+type Reward struct {
+	Name   string   `json:"name"`
+	Config string   `json:"config"`
+	Tasks  []string `json:"tasks,omitempty"`
+}
+
+// EarnedReward records one reward won by a claim
+type EarnedReward struct {
+	Reward   string    `json:"reward"`
+	Task     string    `json:"task"`
+	EarnedAt time.Time `json:"earned_at"`
+}
+
+var rewardsCmd = &cobra.Command{
+	Use:   "rewards",
+	Short: "List rewards, their rarity and how often they were earned",
+	Long: `Rewards turn finished tasks into rolls. Each reward is tied to a roll
+configuration, whose chance and pity decide how rare it is:
+
+  roll rewards add "Fancy coffee" --config coffee --task workout
+  roll rewards claim workout
+
+Claiming a task rolls the config of every reward tied to it and records the
+rewards that come up.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		rewards, err := loadRewards()
+		if err != nil {
+			log.Fatal("Failed to load rewards:", err)
+		}
+		if len(rewards) == 0 {
+			fmt.Println("No rewards defined. Add one with: roll rewards add [reward] --config [name]")
+			return
+		}
+
+		earned := map[string]int{}
+		err = loadHistory("rewards_earned", func(v []byte) error {
+			var e EarnedReward
+			if err := json.Unmarshal(v, &e); err != nil {
+				return err
+			}
+			earned[e.Reward]++
+			return nil
+		})
+		if err != nil {
+			log.Fatal("Failed to load earned rewards:", err)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if !a11y {
+			fmt.Fprintln(w, "REWARD\tCONFIG\tTASKS\tCHANCE\tRARITY\tEARNED")
+		}
+		for _, reward := range rewards {
+			chance := "?"
+			rarity := "unknown"
+			if config, err := loadConfig(reward.Config); err == nil {
+				if state, err := viewState(reward.Config); err == nil {
+					if p, err := successChance(config, pityLevel(config, state, time.Now())); err == nil {
+						chance, rarity = formatPercent(p), rarityName(p)
+					}
+				}
+			}
+			tasks := "any"
+			if len(reward.Tasks) > 0 {
+				tasks = strings.Join(reward.Tasks, ", ")
+			}
+
+			if a11y {
+				fmt.Printf("%s: rolled with %s after %s tasks. Chance %s, %s. Earned %d times.\n",
+					reward.Name, reward.Config, tasks, chance, rarity, earned[reward.Name])
+				continue
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\n",
+				reward.Name, reward.Config, tasks, chance, rarity, earned[reward.Name])
+		}
+		w.Flush()
+	},
+}
+
+var rewardsAddCmd = &cobra.Command{
+	Use:   "add [reward]",
+	Short: "Add or replace a reward tied to a configuration",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		configName, _ := cmd.Flags().GetString("config")
+		tasks, _ := cmd.Flags().GetStringSlice("task")
+
+		if configName == "" {
+			log.Fatal("Specify the configuration to roll with --config")
+		}
+		if _, err := loadConfig(configName); err != nil {
+			log.Fatal("Failed to load config:", err)
+		}
+
+		reward := Reward{Name: args[0], Config: configName, Tasks: tasks}
+		err := db.Update(func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists([]byte("rewards"))
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(reward)
+			if err != nil {
+				return err
+			}
+			return b.Put([]byte(reward.Name), data)
+		})
+		if err != nil {
+			log.Fatal("Failed to save reward:", err)
+		}
+
+		fmt.Printf("Added reward '%s', rolled with '%s'\n", reward.Name, configName)
+	},
+}
+
+var rewardsRemoveCmd = &cobra.Command{
+	Use:   "remove [reward]",
+	Short: "Remove a reward",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := db.Update(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte("rewards"))
+			if b == nil || b.Get([]byte(args[0])) == nil {
+				return fmt.Errorf("no reward named '%s'", args[0])
+			}
+			return b.Delete([]byte(args[0]))
+		})
+		if err != nil {
+			log.Fatal("Failed to remove reward:", err)
+		}
+
+		fmt.Printf("Removed reward '%s'\n", args[0])
+	},
+}
+
+var rewardsClaimCmd = &cobra.Command{
+	Use:   "claim [task]",
+	Short: "Mark a task done and roll for every reward tied to it",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		task := args[0]
+
+		rewards, err := loadRewards()
+		if err != nil {
+			log.Fatal("Failed to load rewards:", err)
+		}
+
+		var won []string
+		rolled := 0
+		for _, reward := range rewards {
+			if len(reward.Tasks) > 0 && !containsString(reward.Tasks, task) {
+				continue
+			}
+			rolled++
+
+			config, err := loadConfig(reward.Config)
+			if err != nil {
+				log.Fatal("Failed to load config:", err)
+			}
+			if err := snapshotConfig(reward.Config); err != nil {
+				log.Fatal("Failed to record config version:", err)
+			}
+
+			if quiet {
+				fmt.Printf("%s\t", reward.Name)
+			} else {
+				fmt.Printf("\nRolling for '%s':", reward.Name)
+			}
+			result, err := rollAndRecord(reward.Config, config)
+			if err != nil {
+				// A locked group only rules out this reward
+				fmt.Printf("Skipped '%s': %v\n", reward.Name, err)
+				continue
+			}
+			if !result.Success {
+				continue
+			}
+
+			won = append(won, reward.Name)
+			err = appendHistory("rewards_earned", EarnedReward{Reward: reward.Name, Task: task, EarnedAt: time.Now().UTC()})
+			if err != nil {
+				log.Fatal("Failed to record reward:", err)
+			}
+		}
+
+		if rolled == 0 {
+			log.Fatalf("No rewards are tied to '%s'", task)
+		}
+
+		// Quiet output already paired each reward with its outcome
+		if quiet {
+			return
+		}
+		fmt.Println()
+		if len(won) == 0 {
+			fmt.Printf("No rewards this time for '%s'\n", task)
+			return
+		}
+		for _, name := range won {
+			if !a11y {
+				fmt.Print("🏆 ")
+			}
+			fmt.Printf("Earned: %s\n", name)
+		}
+	},
+}
+
+func init() {
+	rewardsCmd.AddCommand(rewardsAddCmd)
+	rewardsCmd.AddCommand(rewardsRemoveCmd)
+	rewardsCmd.AddCommand(rewardsClaimCmd)
+	rewardsAddCmd.Flags().String("config", "", "Configuration whose roll decides if the reward is earned")
+	rewardsAddCmd.Flags().StringSlice("task", nil, "Tasks that roll for this reward (default any task)")
+}
+
+// loadRewards returns every reward, sorted by name
+func loadRewards() ([]Reward, error) {
+	var rewards []Reward
+	err := db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("rewards"))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var reward Reward
+			if err := json.Unmarshal(v, &reward); err != nil {
+				return err
+			}
+			rewards = append(rewards, reward)
+			return nil
+		})
+	})
+	sort.Slice(rewards, func(i, j int) bool { return rewards[i].Name < rewards[j].Name })
+	return rewards, err
+}
+
+// rarityName buckets a chance of success into a familiar rarity
+func rarityName(p float64) string {
+	switch {
+	case p >= 0.5:
+		return "common"
+	case p >= 0.2:
+		return "uncommon"
+	case p >= 0.05:
+		return "rare"
+	}
+	return "legendary"
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"container/heap"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var sampleCmd = &cobra.Command{
+	Use:   "sample",
+	Short: "Sample lines from stdin without replacement, optionally weighted",
+	Long: `Pick -n lines from stdin without replacement, in one pass and without
+holding more than n lines in memory:
+
+  ls | roll sample -n 3
+  roll sample -n 5 --weighted --field 2 < songs.txt
+
+With --weighted, the --field column (counting from 1, split on whitespace
+or --delimiter) holds each line's weight and lines are picked in proportion
+to it. Picked lines are printed whole, in random order.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		n, _ := cmd.Flags().GetInt("count")
+		weighted, _ := cmd.Flags().GetBool("weighted")
+		field, _ := cmd.Flags().GetInt("field")
+		delimiter, _ := cmd.Flags().GetString("delimiter")
+
+		if n < 1 {
+			log.Fatal("-n must be at least 1")
+		}
+		if weighted && field < 1 {
+			log.Fatal("--field counts from 1")
+		}
+
+		if verbose {
+			printProvenance()
+		}
+
+		var picked []string
+		var err error
+		if weighted {
+			picked, err = sampleWeighted(os.Stdin, n, field, delimiter)
+		} else {
+			picked, err = sampleReservoir(os.Stdin, n)
+		}
+		if err != nil {
+			log.Fatal("Failed to sample: ", err)
+		}
+
+		for _, line := range picked {
+			fmt.Println(line)
+		}
+	},
+}
+
+func init() {
+	sampleCmd.Flags().IntP("count", "n", 1, "Number of lines to pick")
+	sampleCmd.Flags().Bool("weighted", false, "Weight lines by the number in --field")
+	sampleCmd.Flags().Int("field", 2, "Column holding the weight, counting from 1")
+	sampleCmd.Flags().String("delimiter", "", "Column separator (default any whitespace)")
+}
+
+// sampleReservoir picks n lines uniformly with reservoir sampling: line i
+// replaces a random kept line with chance n/i
+func sampleReservoir(f *os.File, n int) ([]string, error) {
+	var kept []string
+	seen := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		seen++
+		if len(kept) < n {
+			kept = append(kept, scanner.Text())
+		} else if j := rng.Intn(seen); j < n {
+			kept[j] = scanner.Text()
+		}
+	}
+	rng.Shuffle(len(kept), func(i, j int) { kept[i], kept[j] = kept[j], kept[i] })
+	return kept, scanner.Err()
+}
+
+// keyedLine is a line with its weighted-reservoir key
+type keyedLine struct {
+	line string
+	key  float64
+}
+
+// lineHeap is a min-heap on key, so the weakest kept line is on top
+type lineHeap []keyedLine
+
+func (h lineHeap) Len() int            { return len(h) }
+func (h lineHeap) Less(i, j int) bool  { return h[i].key < h[j].key }
+func (h lineHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *lineHeap) Push(x interface{}) { *h = append(*h, x.(keyedLine)) }
+func (h *lineHeap) Pop() interface{} {
+	old := *h
+	item := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return item
+}
+
+// sampleWeighted picks n lines in proportion to their weights with the
+// Efraimidis-Spirakis algorithm: each line gets the key u^(1/weight) for a
+// uniform u, and the n largest keys win. Keys are compared as logs to keep
+// small weights from underflowing.
+func sampleWeighted(f *os.File, n, field int, delimiter string) ([]string, error) {
+	h := &lineHeap{}
+	number := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		number++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		var fields []string
+		if delimiter == "" {
+			fields = strings.Fields(line)
+		} else {
+			fields = strings.Split(line, delimiter)
+		}
+		if field > len(fields) {
+			return nil, fmt.Errorf("line %d has no field %d", number, field)
+		}
+		weight, err := strconv.ParseFloat(strings.TrimSpace(fields[field-1]), 64)
+		if err != nil || weight < 0 || math.IsNaN(weight) || math.IsInf(weight, 0) {
+			return nil, fmt.Errorf("line %d: weight %q must be a non-negative number", number, fields[field-1])
+		}
+		if weight == 0 {
+			continue
+		}
+
+		key := math.Log(1-rng.Float64()) / weight
+		switch {
+		case h.Len() < n:
+			heap.Push(h, keyedLine{line, key})
+		case key > (*h)[0].key:
+			(*h)[0] = keyedLine{line, key}
+			heap.Fix(h, 0)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// Largest key first is itself a weighted random order
+	picked := make([]string, h.Len())
+	for i := len(picked) - 1; i >= 0; i-- {
+		picked[i] = heap.Pop(h).(keyedLine).line
+	}
+	return picked, nil
+}
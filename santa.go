@@ -0,0 +1,272 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/big"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// maxSantaAttempts bounds how many shuffles santa tries before deciding the
+// exclusions cannot be met
+const maxSantaAttempts = 10000
+
+// SantaDraw is a stored secret-santa draw. Each giver's recipient is sealed
+// with a key derived from that giver's token, so the draw can only be read
+// one person at a time.
+type SantaDraw struct {
+	DrawnAt time.Time         `json:"drawn_at"`
+	Sealed  map[string]string `json:"sealed"`
+}
+
+var santaCmd = &cobra.Command{
+	Use:   "santa",
+	Short: "Run a secret-santa draw with sealed, token-protected assignments",
+}
+
+var santaDrawCmd = &cobra.Command{
+	Use:   "draw",
+	Short: "Draw who gives to whom and print a private token for each person",
+	Long: `Draw a secret-santa assignment for the people listed in a file (one per
+line). Nobody draws themselves, and an exclusions file of name:name lines
+keeps those two from drawing each other.
+
+Only the tokens are printed; hand each person their own token so they can
+run santa reveal. Drawing again under the same --event replaces the old draw.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		from, _ := cmd.Flags().GetString("from")
+		exclusionsPath, _ := cmd.Flags().GetString("exclusions")
+		event, _ := cmd.Flags().GetString("event")
+
+		if from == "" {
+			log.Fatal("Specify a file of people with --from")
+		}
+		people, err := readLines(from)
+		if err != nil {
+			log.Fatal("Failed to read people:", err)
+		}
+		if len(people) < 2 {
+			log.Fatal("A draw needs at least two people")
+		}
+		index := map[string]int{}
+		for i, person := range people {
+			if _, ok := index[person]; ok {
+				log.Fatalf("'%s' is listed twice", person)
+			}
+			index[person] = i
+		}
+
+		var exclusions [][2]int
+		if exclusionsPath != "" {
+			lines, err := readLines(exclusionsPath)
+			if err != nil {
+				log.Fatal("Failed to read exclusions:", err)
+			}
+			exclusions, err = parsePairs(lines, index)
+			if err != nil {
+				log.Fatal("Invalid exclusions: ", err)
+			}
+		}
+
+		receivers, err := derange(len(people), exclusions)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		draw := SantaDraw{DrawnAt: time.Now().UTC(), Sealed: map[string]string{}}
+		tokens := make([]string, len(people))
+		for i, giver := range people {
+			token := make([]byte, 8)
+			if _, err := crand.Read(token); err != nil {
+				log.Fatal("Failed to create token:", err)
+			}
+			tokens[i] = hex.EncodeToString(token)
+
+			sealed, err := sealFor(tokens[i], people[receivers[i]])
+			if err != nil {
+				log.Fatal("Failed to seal assignment:", err)
+			}
+			draw.Sealed[giver] = sealed
+		}
+
+		err = db.Update(func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucketIfNotExists([]byte("santa"))
+			if err != nil {
+				return err
+			}
+			data, err := json.Marshal(draw)
+			if err != nil {
+				return err
+			}
+			return b.Put([]byte(event), data)
+		})
+		if err != nil {
+			log.Fatal("Failed to save draw:", err)
+		}
+
+		if quiet {
+			for i, person := range people {
+				fmt.Printf("%s\t%s\n", person, tokens[i])
+			}
+			return
+		}
+
+		if !a11y {
+			fmt.Print("🎁 ")
+		}
+		fmt.Printf("Drew '%s' for %d people. Give each person their token:\n", event, len(people))
+		for i, person := range people {
+			fmt.Printf("  %s: %s\n", person, tokens[i])
+		}
+		fmt.Printf("\nReveal with: roll santa reveal [name] --token [token] --event %s\n", event)
+	},
+}
+
+var santaRevealCmd = &cobra.Command{
+	Use:   "reveal [name]",
+	Short: "Show who a person gives to, using their token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+		token, _ := cmd.Flags().GetString("token")
+		event, _ := cmd.Flags().GetString("event")
+
+		var draw SantaDraw
+		err := db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte("santa"))
+			if b == nil {
+				return fmt.Errorf("no draw named '%s'", event)
+			}
+			data := b.Get([]byte(event))
+			if data == nil {
+				return fmt.Errorf("no draw named '%s'", event)
+			}
+			return json.Unmarshal(data, &draw)
+		})
+		if err != nil {
+			log.Fatal("Failed to load draw:", err)
+		}
+
+		sealed, ok := draw.Sealed[name]
+		if !ok {
+			log.Fatalf("'%s' is not in the '%s' draw", name, event)
+		}
+		receiver, err := unsealFor(token, sealed)
+		if err != nil {
+			log.Fatal("Wrong token for ", name)
+		}
+
+		if quiet {
+			fmt.Println(receiver)
+			return
+		}
+		if !a11y {
+			fmt.Print("🎁 ")
+		}
+		fmt.Printf("%s gives to %s\n", name, receiver)
+	},
+}
+
+func init() {
+	santaCmd.AddCommand(santaDrawCmd)
+	santaCmd.AddCommand(santaRevealCmd)
+	santaCmd.PersistentFlags().String("event", "santa", "Name of the draw, to keep several apart")
+	santaDrawCmd.Flags().String("from", "", "File listing one person per line")
+	santaDrawCmd.Flags().String("exclusions", "", "File of name:name lines for people who must not draw each other")
+	santaRevealCmd.Flags().String("token", "", "The token handed out at the draw")
+}
+
+// derange returns a random receiver for each of n givers such that nobody
+// receives from themselves and excluded pairs never draw each other. It
+// shuffles with crypto/rand: the seeded rng could be recovered from the draw
+// time, which would give away every pairing without a token.
+func derange(n int, exclusions [][2]int) ([]int, error) {
+	excluded := map[[2]int]bool{}
+	for _, e := range exclusions {
+		excluded[[2]int{e[0], e[1]}] = true
+		excluded[[2]int{e[1], e[0]}] = true
+	}
+
+	receivers := make([]int, n)
+	for i := range receivers {
+		receivers[i] = i
+	}
+	for attempt := 0; attempt < maxSantaAttempts; attempt++ {
+		if err := secureShuffle(receivers); err != nil {
+			return nil, err
+		}
+		ok := true
+		for giver, receiver := range receivers {
+			if giver == receiver || excluded[[2]int{giver, receiver}] {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return receivers, nil
+		}
+	}
+	return nil, fmt.Errorf("no draw found that honors every exclusion after %d tries", maxSantaAttempts)
+}
+
+// secureShuffle shuffles items in place with a Fisher-Yates shuffle drawing
+// from crypto/rand
+func secureShuffle(items []int) error {
+	for i := len(items) - 1; i > 0; i-- {
+		j, err := crand.Int(crand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return err
+		}
+		items[i], items[j.Int64()] = items[j.Int64()], items[i]
+	}
+	return nil
+}
+
+// sealFor encrypts a recipient with AES-GCM under a key derived from token
+func sealFor(token, receiver string) (string, error) {
+	gcm, err := santaCipher(token)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := crand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(gcm.Seal(nonce, nonce, []byte(receiver), nil)), nil
+}
+
+// unsealFor reverses sealFor, failing if the token is wrong
+func unsealFor(token, sealed string) (string, error) {
+	data, err := hex.DecodeString(sealed)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := santaCipher(token)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", fmt.Errorf("sealed assignment is too short")
+	}
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	return string(plain), err
+}
+
+func santaCipher(token string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(token))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
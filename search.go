@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+// SearchResult is one thing that matched a search and how to act on it
+type SearchResult struct {
+	Kind    string
+	Name    string
+	Match   string
+	Command string
+}
+
+// searchSources lists everything search looks through, in the order results
+// are shown. Each source returns the results whose text contains the query.
+var searchSources = []struct {
+	Kind   string
+	Search func(query string) ([]SearchResult, error)
+}{
+	{"config", searchConfigs},
+	{"table", searchTables},
+	{"wordlist", searchWordlists},
+	{"reward", searchRewards},
+	{"macro", searchMacros},
+	{"dice", searchDiceHistory},
+	{"oracle", searchOracleHistory},
+}
+
+var searchCmd = &cobra.Command{
+	Use:   "search [text]",
+	Short: "Search configs, tables, wordlists, rewards, macros and history",
+	Long: `Find everything mentioning some text, ignoring case:
+
+  roll search zhongli
+  roll search dragon --type table,oracle
+
+Configs match on their name, tags and groups, tables on their name and
+entries, rewards on their name, config and tasks, macros on their name and
+expression, dice history on the expression and label, and oracle history
+on the question. Each result comes with a command to act on it.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		types, _ := cmd.Flags().GetStringSlice("type")
+		query := strings.ToLower(args[0])
+
+		wanted := map[string]bool{}
+		for _, kind := range types {
+			found := false
+			for _, source := range searchSources {
+				found = found || source.Kind == kind
+			}
+			if !found {
+				log.Fatalf("Unknown type '%s'; use %s", kind, strings.Join(searchKinds(), ", "))
+			}
+			wanted[kind] = true
+		}
+
+		var results []SearchResult
+		for _, source := range searchSources {
+			if len(wanted) > 0 && !wanted[source.Kind] {
+				continue
+			}
+			found, err := source.Search(query)
+			if err != nil {
+				log.Fatalf("Failed to search %ss: %v", source.Kind, err)
+			}
+			results = append(results, found...)
+		}
+
+		if len(results) == 0 {
+			if !quiet {
+				fmt.Printf("Nothing matches '%s'\n", args[0])
+			}
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if !quiet && !a11y {
+			fmt.Fprintln(w, "TYPE\tNAME\tMATCH\tCOMMAND")
+		}
+		for _, r := range results {
+			switch {
+			case quiet:
+				fmt.Printf("%s\t%s\n", r.Kind, r.Name)
+			case a11y:
+				fmt.Printf("%s %s, matching %s. Run: %s.\n", strings.ToUpper(r.Kind[:1])+r.Kind[1:], r.Name, r.Match, r.Command)
+			default:
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Kind, r.Name, r.Match, r.Command)
+			}
+		}
+		w.Flush()
+	},
+}
+
+func init() {
+	searchCmd.Flags().StringSlice("type", nil, "Only search these types, e.g. config,table")
+	searchCmd.RegisterFlagCompletionFunc("type", completeFlag(searchKinds))
+}
+
+func searchKinds() []string {
+	kinds := make([]string, len(searchSources))
+	for i, source := range searchSources {
+		kinds[i] = source.Kind
+	}
+	return kinds
+}
+
+// matches reports whether text contains the lower-cased query
+func matches(text, query string) bool {
+	return strings.Contains(strings.ToLower(text), query)
+}
+
+// shellArg quotes an argument for a suggested command when it needs it
+func shellArg(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\"'$`\\|&;<>()*?!#~") {
+		return fmt.Sprintf("%q", s)
+	}
+	return s
+}
+
+func searchConfigs(query string) ([]SearchResult, error) {
+	var results []SearchResult
+	for _, name := range configNames() {
+		command := "roll show " + shellArg(name)
+		if matches(name, query) {
+			results = append(results, SearchResult{"config", name, "name", command})
+			continue
+		}
+		config, err := loadConfig(name)
+		if err != nil {
+			continue
+		}
+		tag := ""
+		for _, t := range config.Tags {
+			if matches(t, query) {
+				tag = t
+				break
+			}
+		}
+		switch {
+		case tag != "":
+			results = append(results, SearchResult{"config", name, "tag " + tag, command})
+		case config.Group != "" && matches(config.Group, query):
+			results = append(results, SearchResult{"config", name, "group " + config.Group, command})
+		case config.SparkGroup != "" && matches(config.SparkGroup, query):
+			results = append(results, SearchResult{"config", name, "spark group " + config.SparkGroup, command})
+		}
+	}
+	return results, nil
+}
+
+func searchTables(query string) ([]SearchResult, error) {
+	var results []SearchResult
+	for _, name := range tableNames() {
+		command := "roll spark --tables " + shellArg(name)
+		if matches(name, query) {
+			results = append(results, SearchResult{"table", name, "name", command})
+			continue
+		}
+		entries, err := loadTable(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			if matches(entry, query) {
+				results = append(results, SearchResult{"table", name, "entry " + entry, command})
+			}
+		}
+	}
+	return results, nil
+}
+
+func searchWordlists(query string) ([]SearchResult, error) {
+	var results []SearchResult
+	for _, name := range wordlistNames() {
+		if matches(name, query) {
+			results = append(results, SearchResult{"wordlist", name, "name", "roll password --wordlist " + shellArg(name)})
+		}
+	}
+	return results, nil
+}
+
+func searchRewards(query string) ([]SearchResult, error) {
+	rewards, err := loadRewards()
+	if err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for _, reward := range rewards {
+		command := "roll rewards"
+		if len(reward.Tasks) > 0 {
+			command = "roll rewards claim " + shellArg(reward.Tasks[0])
+		}
+		match := ""
+		switch {
+		case matches(reward.Name, query):
+			match = "name"
+		case matches(reward.Config, query):
+			match = "config " + reward.Config
+			command = "roll show " + shellArg(reward.Config)
+		default:
+			for _, task := range reward.Tasks {
+				if matches(task, query) {
+					match = "task " + task
+					command = "roll rewards claim " + shellArg(task)
+					break
+				}
+			}
+		}
+		if match != "" {
+			results = append(results, SearchResult{"reward", reward.Name, match, command})
+		}
+	}
+	return results, nil
+}
+
+func searchMacros(query string) ([]SearchResult, error) {
+	macros, err := loadMacros()
+	if err != nil {
+		return nil, err
+	}
+	var results []SearchResult
+	for _, name := range macroNames() {
+		match := ""
+		switch {
+		case matches(name, query):
+			match = "name"
+		case matches(macros[name], query):
+			match = "expression " + macros[name]
+		}
+		if match != "" {
+			results = append(results, SearchResult{"macro", name, match, "roll dice " + shellArg(name)})
+		}
+	}
+	return results, nil
+}
+
+// searchDiceHistory returns matching dice rolls, newest first, once each
+func searchDiceHistory(query string) ([]SearchResult, error) {
+	records, err := loadDiceHistory()
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	var results []SearchResult
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		command := "roll dice " + record.Command()
+		if seen[command] {
+			continue
+		}
+		match := ""
+		switch {
+		case record.Label != "" && matches(record.Label, query):
+			match = "label " + record.Label
+		case matches(record.Expr, query):
+			match = "expression"
+		}
+		if match != "" {
+			seen[command] = true
+			results = append(results, SearchResult{"dice", record.Expr, match, command})
+		}
+	}
+	return results, nil
+}
+
+// searchOracleHistory returns matching oracle questions, newest first
+func searchOracleHistory(query string) ([]SearchResult, error) {
+	var results []SearchResult
+	err := loadHistory("oracle_history", func(v []byte) error {
+		var record OracleRecord
+		if err := json.Unmarshal(v, &record); err != nil {
+			return err
+		}
+		if matches(record.Question, query) {
+			command := fmt.Sprintf("roll oracle %s --likelihood %s", shellArg(record.Question), record.Likelihood)
+			result := SearchResult{"oracle", record.Question, "answered " + record.Answer, command}
+			results = append([]SearchResult{result}, results...)
+		}
+		return nil
+	})
+	return results, err
+}
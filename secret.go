@@ -0,0 +1,128 @@
+package main
+
+import (
+	crand "crypto/rand"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"math/big"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// maxSecretBytes bounds token --bytes and password --words
+const maxSecretBytes = 1024
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Generate a random token or UUID from the system's secure random source",
+	Long: `Generate a token from crypto/rand, never the seeded source the dice use:
+
+  roll token --bytes 32 --encoding base64url
+  roll token --encoding uuid
+
+Encodings are hex (the default), base64, base64url, base32 and uuid, a
+version 4 UUID that ignores --bytes.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		size, _ := cmd.Flags().GetInt("bytes")
+		encoding, _ := cmd.Flags().GetString("encoding")
+
+		if size < 1 || size > maxSecretBytes {
+			log.Fatalf("Bytes must be between 1 and %d", maxSecretBytes)
+		}
+		if encoding == "uuid" {
+			size = 16
+		}
+		data := make([]byte, size)
+		if _, err := crand.Read(data); err != nil {
+			log.Fatal("Failed to read random bytes:", err)
+		}
+
+		var token string
+		switch encoding {
+		case "hex":
+			token = hex.EncodeToString(data)
+		case "base64":
+			token = base64.StdEncoding.EncodeToString(data)
+		case "base64url":
+			token = base64.RawURLEncoding.EncodeToString(data)
+		case "base32":
+			token = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(data)
+		case "uuid":
+			data[6] = data[6]&0x0f | 0x40 // version 4
+			data[8] = data[8]&0x3f | 0x80 // RFC 4122 variant
+			h := hex.EncodeToString(data)
+			token = h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+		default:
+			log.Fatalf("Unknown encoding '%s'; use hex, base64, base64url, base32 or uuid", encoding)
+		}
+
+		if verbose {
+			fmt.Println("RNG: crypto/rand")
+		}
+		fmt.Println(token)
+	},
+}
+
+var passwordCmd = &cobra.Command{
+	Use:   "password",
+	Short: "Generate a diceware passphrase from the secure random source",
+	Long: `Generate a passphrase of random words, each picked with crypto/rand from
+the bundled wordlist or one installed with wordlist add:
+
+  roll password --words 5
+  roll password --wordlist eff-large
+
+The entropy printed alongside is log2(wordlist size) bits per word.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		count, _ := cmd.Flags().GetInt("words")
+		separator, _ := cmd.Flags().GetString("separator")
+		name, _ := cmd.Flags().GetString("wordlist")
+		if name == "" {
+			name = defaultWordlist()
+		}
+
+		if count < 1 || count > maxSecretBytes {
+			log.Fatalf("Words must be between 1 and %d", maxSecretBytes)
+		}
+		words, err := loadWordlist(name)
+		if err != nil {
+			log.Fatal("Failed to load wordlist: ", err)
+		}
+
+		picked := make([]string, count)
+		for i := range picked {
+			n, err := crand.Int(crand.Reader, big.NewInt(int64(len(words))))
+			if err != nil {
+				log.Fatal("Failed to read random bytes:", err)
+			}
+			picked[i] = words[n.Int64()]
+		}
+		passphrase := strings.Join(picked, separator)
+		bits := float64(count) * math.Log2(float64(len(words)))
+
+		if verbose {
+			fmt.Println("RNG: crypto/rand")
+		}
+		fmt.Println(passphrase)
+		if !quiet {
+			fmt.Printf("Entropy: about %s bits (%d words from a list of %s)\n",
+				formatFloat(bits, 1), count, formatCount(len(words)))
+		}
+	},
+}
+
+func init() {
+	tokenCmd.Flags().Int("bytes", 32, "Random bytes in the token")
+	tokenCmd.Flags().String("encoding", "hex", "hex, base64, base64url, base32 or uuid")
+	passwordCmd.Flags().Int("words", 6, "Number of words in the passphrase")
+	passwordCmd.Flags().String("separator", "-", "Text placed between words")
+	passwordCmd.Flags().String("wordlist", "", "Wordlist to pick from (default the wordlist setting, or bundled)")
+	passwordCmd.RegisterFlagCompletionFunc("wordlist", completeFlag(wordlistNames))
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+)
+
+// settingsName is the file in the config directory holding user settings.
+// It shares the directory with roll configurations, so it is reserved as a
+// config name.
+const settingsName = "settings"
+
+// Settings holds user preferences from settings.toml
+type Settings struct {
+	Strict   bool   `toml:"strict"`
+	Timezone string `toml:"timezone"`
+
+	// Number formatting: decimals shown for percentages and averages, and
+	// the locale whose separators are used (defaults to $LANG)
+	Precision int    `toml:"precision"`
+	Locale    string `toml:"locale"`
+
+	// Wordlist is the list password picks from by default
+	Wordlist string `toml:"wordlist"`
+
+	// TrashDays is how long deleted items stay restorable; 0 keeps them
+	TrashDays int `toml:"trash_days"`
+
+	// GroupPeriod is how long a group stays locked after a success: day,
+	// week or month. Empty keeps it locked until group reset.
+	GroupPeriod string `toml:"group_period"`
+
+	// Per-command defaults keyed by command, e.g. roll = "quiet" under
+	// [verbosity] or roll = "a11y" under [format]
+	Verbosity map[string]string `toml:"verbosity"`
+	Format    map[string]string `toml:"format"`
+}
+
+var (
+	settings = Settings{Precision: 2, TrashDays: 30}
+	strict   bool
+	timezone string
+
+	// displayLocation is where stored UTC timestamps are shown
+	displayLocation = time.Local
+)
+
+func init() {
+	rootCmd.PersistentFlags().BoolVar(&strict, "strict", false, "Treat missing state and unknown config keys as errors")
+	rootCmd.PersistentFlags().StringVar(&timezone, "tz", "", "Timezone for displayed times, e.g. Europe/Berlin (default local)")
+}
+
+// loadSettings reads settings.toml if it exists, overrides it with ROLL_*
+// environment variables, and applies the result to any flags that were not
+// given on the command line
+func loadSettings(cmd *cobra.Command) error {
+	settingsPath := filepath.Join(configDir, settingsName+".toml")
+	var undecoded []toml.Key
+	if _, err := os.Stat(settingsPath); err == nil {
+		meta, err := toml.DecodeFile(settingsPath, &settings)
+		if err != nil {
+			return err
+		}
+		undecoded = meta.Undecoded()
+	}
+
+	if err := applySettingsEnv(&settings); err != nil {
+		return err
+	}
+
+	if !cmd.Flags().Changed("strict") {
+		strict = settings.Strict
+	}
+
+	// Typos decode to nothing, so call out keys that matched no setting
+	if len(undecoded) > 0 {
+		msg := fmt.Sprintf("unknown keys in %s: %s", settingsPath, describeUnknownKeys(undecoded, tomlKeys(Settings{})))
+		if strict {
+			return fmt.Errorf("%s", msg)
+		}
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", msg)
+	}
+	if !cmd.Flags().Changed("tz") {
+		timezone = settings.Timezone
+	}
+
+	if err := applyCommandDefaults(cmd); err != nil {
+		return err
+	}
+
+	// Machine mode wants plain, predictable output whatever the defaults
+	if ci {
+		a11y, quiet, verbose = true, false, false
+	}
+
+	if settings.Precision < 0 {
+		return fmt.Errorf("precision must be non-negative")
+	}
+	switch settings.GroupPeriod {
+	case "", "day", "week", "month":
+	default:
+		return fmt.Errorf("group_period must be day, week or month")
+	}
+	setLocale(settings.Locale)
+
+	if timezone != "" {
+		loc, err := time.LoadLocation(timezone)
+		if err != nil {
+			return fmt.Errorf("invalid timezone: %w", err)
+		}
+		displayLocation = loc
+	}
+	return nil
+}
+
+// applyCommandDefaults sets the output flags from the per-command verbosity
+// and format settings, unless they were given on the command line
+func applyCommandDefaults(cmd *cobra.Command) error {
+	key := strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" ")
+	flags := cmd.Flags()
+
+	if !flags.Changed("quiet") && !flags.Changed("verbose") {
+		switch settings.Verbosity[key] {
+		case "", "normal":
+		case "quiet":
+			quiet = true
+		case "verbose":
+			verbose = true
+		default:
+			return fmt.Errorf("verbosity for %s must be quiet, normal or verbose", key)
+		}
+	}
+
+	if !flags.Changed("a11y") {
+		switch settings.Format[key] {
+		case "", "standard":
+		case "a11y":
+			a11y = true
+		default:
+			return fmt.Errorf("format for %s must be standard or a11y", key)
+		}
+	}
+	return nil
+}
+
+// formatTime shows a stored timestamp in the display timezone
+func formatTime(t time.Time) string {
+	return t.In(displayLocation).Format("2006-01-02 15:04:05 MST")
+}
+
+// applySettingsEnv overrides settings from environment variables named after
+// their TOML keys, e.g. strict is read from ROLL_STRICT. Per-command tables
+// take one variable per command, e.g. ROLL_VERBOSITY_DICE_HISTORY=quiet sets
+// "dice history" under [verbosity].
+func applySettingsEnv(s *Settings) error {
+	v := reflect.ValueOf(s).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key, _, _ := strings.Cut(t.Field(i).Tag.Get("toml"), ",")
+		env := "ROLL_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		field := v.Field(i)
+
+		if field.Kind() == reflect.Map {
+			if _, ok := os.LookupEnv(env); ok {
+				return fmt.Errorf("%s needs a command, as in %s_ROLL", env, env)
+			}
+			for _, kv := range os.Environ() {
+				name, value, _ := strings.Cut(kv, "=")
+				command, ok := strings.CutPrefix(name, env+"_")
+				if !ok || command == "" {
+					continue
+				}
+				if field.IsNil() {
+					field.Set(reflect.MakeMap(field.Type()))
+				}
+				command = strings.ToLower(strings.ReplaceAll(command, "_", " "))
+				field.SetMapIndex(reflect.ValueOf(command), reflect.ValueOf(value))
+			}
+			continue
+		}
+
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", env, err)
+			}
+			field.SetBool(b)
+		case reflect.Int:
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", env, err)
+			}
+			field.SetInt(int64(n))
+		case reflect.String:
+			field.SetString(value)
+		default:
+			return fmt.Errorf("%s cannot be set from the environment", env)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,260 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// SparkState counts rolls made across a spark group
+type SparkState struct {
+	Count int `json:"count"`
+}
+
+var sparkCmd = &cobra.Command{
+	Use:   "spark",
+	Short: "Manage spark counters, or draw story prompts with --tables",
+	Long: `Manage spark counters shared across configurations.
+
+With --tables, draw one entry from each named inspiration table instead,
+e.g. roll spark --tables action,theme. See spark tables for what is
+available; files in the tables directory add to or replace the bundled ones.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		tables, _ := cmd.Flags().GetStringSlice("tables")
+		if len(tables) == 0 {
+			cmd.Help()
+			return
+		}
+
+		draws := make([]string, len(tables))
+		for i, name := range tables {
+			entries, err := loadTable(name)
+			if err != nil {
+				log.Fatal("Failed to load table:", err)
+			}
+			draws[i] = entries[rng.Intn(len(entries))]
+		}
+
+		if quiet {
+			fmt.Println(strings.Join(draws, " "))
+			return
+		}
+		if a11y {
+			for i, name := range tables {
+				fmt.Printf("%s: %s.\n", name, draws[i])
+			}
+			return
+		}
+
+		fmt.Printf("\n✨ %s\n", strings.Join(draws, " / "))
+		for i, name := range tables {
+			fmt.Printf("  %s: %s\n", name, draws[i])
+		}
+	},
+}
+
+var sparkStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show spark counters for every spark group",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		sparks := map[string]SparkState{}
+		err := db.View(func(tx *bolt.Tx) error {
+			b := tx.Bucket([]byte("sparks"))
+			if b == nil {
+				return nil
+			}
+			return b.ForEach(func(k, v []byte) error {
+				var spark SparkState
+				if err := json.Unmarshal(v, &spark); err != nil {
+					return err
+				}
+				sparks[string(k)] = spark
+				return nil
+			})
+		})
+		if err != nil {
+			log.Fatal("Failed to load sparks:", err)
+		}
+
+		// Thresholds come from the member configs, each redeeming at its own
+		thresholds := map[string]map[string]int{}
+		for _, name := range configNames() {
+			config, err := loadConfig(name)
+			if err != nil || config.SparkGroup == "" {
+				continue
+			}
+			if thresholds[config.SparkGroup] == nil {
+				thresholds[config.SparkGroup] = map[string]int{}
+			}
+			thresholds[config.SparkGroup][name] = config.Spark
+			if _, ok := sparks[config.SparkGroup]; !ok {
+				sparks[config.SparkGroup] = SparkState{}
+			}
+		}
+
+		if len(sparks) == 0 {
+			fmt.Println("No spark groups defined")
+			return
+		}
+
+		groups := make([]string, 0, len(sparks))
+		for group := range sparks {
+			groups = append(groups, group)
+		}
+		sort.Strings(groups)
+
+		for _, group := range groups {
+			count := sparks[group].Count
+			members := make([]string, 0, len(thresholds[group]))
+			shared := true
+			for name, threshold := range thresholds[group] {
+				members = append(members, name)
+				shared = shared && threshold == thresholds[group][members[0]]
+			}
+			sort.Strings(members)
+
+			// Members with different thresholds are listed one by one
+			if len(members) == 0 || !shared {
+				fmt.Printf("%s: %d\n", group, count)
+				for _, name := range members {
+					fmt.Printf("  %s: %s\n", name, sparkProgress(count, thresholds[group][name]))
+				}
+				continue
+			}
+			fmt.Printf("%s: %s\n", group, sparkProgress(count, thresholds[group][members[0]]))
+		}
+	},
+}
+
+var sparkRedeemCmd = &cobra.Command{
+	Use:   "redeem [name]",
+	Short: "Spend a full spark counter on a guaranteed success for a configuration",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name := args[0]
+
+		config, err := loadConfig(name)
+		if err != nil {
+			log.Fatal("Failed to load config:", err)
+		}
+		if config.SparkGroup == "" {
+			log.Fatalf("'%s' is not in a spark group", name)
+		}
+
+		var remaining int
+		err = db.Update(func(tx *bolt.Tx) error {
+			spark, err := getSparkState(tx, config.SparkGroup)
+			if err != nil {
+				return err
+			}
+			if spark.Count < config.Spark {
+				return fmt.Errorf("spark '%s' is at %d/%d", config.SparkGroup, spark.Count, config.Spark)
+			}
+			if err := checkGroupLock(tx, config); err != nil {
+				return err
+			}
+
+			spark.Count -= config.Spark
+			remaining = spark.Count
+			if err := putSparkState(tx, config.SparkGroup, spark); err != nil {
+				return err
+			}
+
+			// A redeemed spark counts as a success
+			b, err := tx.CreateBucketIfNotExists([]byte("states"))
+			if err != nil {
+				return err
+			}
+			var state State
+			if data := b.Get([]byte(name)); data != nil {
+				if err := json.Unmarshal(data, &state); err != nil {
+					return err
+				}
+			}
+			state.PityCounter = 0
+			state.LastSuccessAt = time.Now().UTC()
+
+			data, err := json.Marshal(state)
+			if err != nil {
+				return err
+			}
+			if err := b.Put([]byte(name), data); err != nil {
+				return err
+			}
+
+			if config.Group != "" {
+				return lockGroup(tx, config.Group, name)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal("Failed to redeem spark:", err)
+		}
+
+		if !a11y {
+			fmt.Print("✨ ")
+		}
+		fmt.Printf("Redeemed spark '%s' for a guaranteed success on '%s'\n", config.SparkGroup, name)
+		fmt.Printf("Spark counter: %d/%d\n", remaining, config.Spark)
+	},
+}
+
+func init() {
+	sparkCmd.AddCommand(sparkStatusCmd)
+	sparkCmd.AddCommand(sparkRedeemCmd)
+	sparkCmd.Flags().StringSlice("tables", nil, "Draw a story prompt from these inspiration tables, e.g. action,theme")
+	sparkCmd.RegisterFlagCompletionFunc("tables", completeFlag(tableNames))
+	sparkRedeemCmd.ValidArgsFunction = completeArgs(configNames)
+}
+
+func getSparkState(tx *bolt.Tx, group string) (SparkState, error) {
+	var spark SparkState
+	b := tx.Bucket([]byte("sparks"))
+	if b == nil {
+		return spark, nil
+	}
+	data := b.Get([]byte(group))
+	if data == nil {
+		return spark, nil
+	}
+	err := json.Unmarshal(data, &spark)
+	return spark, err
+}
+
+func putSparkState(tx *bolt.Tx, group string, spark SparkState) error {
+	b, err := tx.CreateBucketIfNotExists([]byte("sparks"))
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(spark)
+	if err != nil {
+		return err
+	}
+	return b.Put([]byte(group), data)
+}
+
+// addSpark counts a roll towards the config's spark group
+func addSpark(tx *bolt.Tx, config *Config) (SparkState, error) {
+	spark, err := getSparkState(tx, config.SparkGroup)
+	if err != nil {
+		return spark, err
+	}
+	spark.Count++
+	return spark, putSparkState(tx, config.SparkGroup, spark)
+}
+
+// sparkProgress shows a spark count against a threshold
+func sparkProgress(count, threshold int) string {
+	if threshold > 0 && count >= threshold {
+		return fmt.Sprintf("%d/%d (ready to redeem)", count, threshold)
+	}
+	return fmt.Sprintf("%d/%d", count, threshold)
+}
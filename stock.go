@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+// stockContents follows the classic d6 stocking ratios: monster on 1-2, trap
+// on 3, special on 4 and empty on 5-6. Treasure is found alongside on a d6
+// roll of at most the given number.
+var stockContents = []struct {
+	Contents string
+	Table    string
+	Treasure int
+}{
+	{"Monster", "monster", 3},
+	{"Monster", "monster", 3},
+	{"Trap", "trap", 2},
+	{"Special", "special", 0},
+	{"Empty", "", 1},
+	{"Empty", "", 1},
+}
+
+var stockCmd = &cobra.Command{
+	Use:   "stock",
+	Short: "Stock dungeon rooms with monsters, traps and treasure as a markdown key",
+	Long: `Stock dungeon rooms using the classic ratios: a d6 gives monster (1-2),
+trap (3), special (4) or empty (5-6), and a second d6 decides whether there
+is treasure. Details come from the monster, trap and special tables, which
+can be replaced like any other table (see spark tables).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		rooms, _ := cmd.Flags().GetInt("dungeon-rooms")
+		if rooms < 1 || rooms > maxRepetitions {
+			log.Fatalf("Dungeon rooms must be between 1 and %d", maxRepetitions)
+		}
+
+		fmt.Printf("# Dungeon key (%d rooms)\n\n", rooms)
+		for room := 1; room <= rooms; room++ {
+			stock := stockContents[rng.Intn(6)]
+			line := fmt.Sprintf("**%s**", stock.Contents)
+
+			if stock.Table != "" {
+				entries, err := loadTable(stock.Table)
+				if err != nil {
+					log.Fatal("Failed to load table:", err)
+				}
+				line += ": " + entries[rng.Intn(len(entries))]
+			}
+			if rng.Intn(6)+1 <= stock.Treasure {
+				line += ", with treasure"
+			}
+			fmt.Printf("%d. %s\n", room, line)
+		}
+	},
+}
+
+func init() {
+	stockCmd.Flags().Int("dungeon-rooms", 0, "Number of rooms to stock")
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// bundledTables are the tables shipped with roll: inspiration tables for
+// spark --tables, room details for stock and NPC parts for npc. A file of the
+// same name in the tables directory replaces the bundled one.
+var bundledTables = map[string][]string{
+	"action": {
+		"abandon", "betray", "bargain", "chase", "conceal", "confront",
+		"deceive", "defend", "discover", "escape", "guide", "hunt",
+		"imprison", "inspire", "investigate", "negotiate", "oppose", "protect",
+		"pursue", "rebel", "recover", "reveal", "sacrifice", "seize",
+		"steal", "summon", "transform", "trap", "uncover", "warn",
+	},
+	"theme": {
+		"ambition", "balance", "change", "corruption", "debt", "duty",
+		"exile", "faith", "fear", "freedom", "grief", "home",
+		"honor", "hope", "identity", "isolation", "justice", "legacy",
+		"love", "loyalty", "memory", "power", "pride", "redemption",
+		"revenge", "secrets", "survival", "time", "trust", "truth",
+	},
+	"descriptor": {
+		"ancient", "broken", "cold", "cursed", "delicate", "desperate",
+		"fading", "forbidden", "forgotten", "gentle", "gilded", "hidden",
+		"hollow", "hungry", "lost", "mysterious", "ominous", "quiet",
+		"restless", "ruined", "sacred", "scarred", "shimmering", "strange",
+		"tangled", "twisted", "unexpected", "vast", "wild", "wounded",
+	},
+	"focus": {
+		"an animal", "an artifact", "a border", "a bridge", "a child", "a city",
+		"a contract", "a crowd", "a door", "a dream", "a feast", "a gift",
+		"a journey", "a key", "a letter", "a machine", "a map", "a market",
+		"a mentor", "a mirror", "a prophecy", "a rival", "a ruin", "a ship",
+		"a song", "a storm", "a stranger", "a tower", "a war", "a well",
+	},
+	"monster": {
+		"bandits", "cultists", "fire beetles", "ghouls", "giant rats",
+		"giant spiders", "gnolls", "goblins", "hobgoblins", "kobolds",
+		"lizardfolk", "ogre", "orcs", "skeletons", "stirges", "zombies",
+	},
+	"trap": {
+		"collapsing ceiling", "crossbow bolt", "falling net", "flooding room",
+		"gas vent", "pendulum blade", "pit", "poison needle",
+		"rolling boulder", "scything blade", "sliding wall", "spiked pit",
+	},
+	"special": {
+		"altar", "echoing chasm", "fountain", "glowing runes", "magic mirror",
+		"shrine", "speaking statue", "strange machine", "talking door",
+		"underground pool",
+	},
+	"name": {
+		"Alda Fenwick", "Bram Holloway", "Cora Vance", "Dain Ashgrove",
+		"Edda Marsh", "Finn Talbot", "Greta Stone", "Hal Brightwater",
+		"Ilse Crowe", "Jory Pike", "Kessa Vale", "Lorcan Reed",
+		"Mira Thorne", "Ned Copperpot", "Odile Frost", "Pell Harrow",
+		"Quinn Lark", "Rosa Millbrook", "Sten Garrow", "Tamsin Wick",
+		"Ulric Fell", "Vera Quill", "Wendel Oakes", "Yara Dunmore",
+		"Zeke Barrow", "Agnes Rook", "Bastian Hale", "Celia Moss",
+		"Doran Kettle", "Elsbeth Wren",
+	},
+	"trait": {
+		"absent-minded", "blunt", "boastful", "cautious", "cheerful", "cynical",
+		"curious", "devout", "flirtatious", "generous", "gruff", "honest",
+		"impatient", "jumpy", "lazy", "loyal", "meticulous", "nosy",
+		"pompous", "quiet", "reckless", "sarcastic", "secretive", "shrewd",
+		"stubborn", "superstitious", "suspicious", "tired", "vain", "warm",
+	},
+	"motivation": {
+		"avenge a murdered sibling", "become famous", "clear their name",
+		"earn a noble title", "escape an arranged marriage", "find a lost child",
+		"find true love", "get rich quick", "guard a family secret",
+		"hide from old enemies", "keep the peace", "leave town for good",
+		"learn forbidden magic", "live a quiet life", "make amends",
+		"outdo a rival", "pay off a debt", "prove their worth",
+		"protect their home", "recover a stolen heirloom", "reunite their family",
+		"serve their god", "settle a grudge", "start a business",
+		"survive the winter", "take over the guild", "uncover a conspiracy",
+		"win a bet", "win back a lost love", "write the great ballad",
+	},
+	"quirk": {
+		"always eating", "bites their nails", "carries a pet rat",
+		"collects buttons", "counts everything", "cracks their knuckles",
+		"fidgets with a coin", "has a missing tooth", "has a nervous laugh",
+		"hums when nervous",
+		"keeps a diary", "mispronounces names", "never makes eye contact",
+		"never sits down", "overuses proverbs", "quotes old poets",
+		"smells of smoke", "speaks in a whisper", "speaks of themselves in the third person",
+		"stutters when lying", "swears by obscure saints", "talks to plants",
+		"taps their foot", "tells long stories", "terrified of birds",
+		"wears too much jewelry", "whistles constantly", "winks a lot",
+		"writes everything down", "yawns constantly",
+	},
+}
+
+var sparkTablesCmd = &cobra.Command{
+	Use:   "tables",
+	Short: "List the tables available to spark --tables, stock and npc",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		names := tableNames()
+		for _, name := range names {
+			entries, err := loadTable(name)
+			if err != nil {
+				log.Fatal("Failed to load table:", err)
+			}
+			if quiet {
+				fmt.Println(name)
+				continue
+			}
+			source := "bundled"
+			if _, err := os.Stat(tablePath(name)); err == nil {
+				source = tablePath(name)
+			}
+			fmt.Printf("%s: %d entries (%s)\n", name, len(entries), source)
+		}
+	},
+}
+
+func init() {
+	sparkCmd.AddCommand(sparkTablesCmd)
+}
+
+// tablePath is where a user-supplied table lives, in the readLines format
+func tablePath(name string) string {
+	return filepath.Join(configDir, "tables", name+".txt")
+}
+
+// tableNames returns every bundled and user-supplied table, sorted
+func tableNames() []string {
+	seen := map[string]bool{}
+	for name := range bundledTables {
+		seen[name] = true
+	}
+	if files, err := os.ReadDir(filepath.Join(configDir, "tables")); err == nil {
+		for _, file := range files {
+			if filepath.Ext(file.Name()) == ".txt" {
+				seen[strings.TrimSuffix(file.Name(), ".txt")] = true
+			}
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadTable returns a table's entries, preferring the user's file over the
+// bundled table of the same name
+func loadTable(name string) ([]string, error) {
+	entries, err := readLines(tablePath(name))
+	if os.IsNotExist(err) {
+		if entries, ok := bundledTables[name]; ok {
+			return entries, nil
+		}
+		return nil, fmt.Errorf("no table named '%s' (available: %s)", name, strings.Join(tableNames(), ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("table '%s' is empty", name)
+	}
+	return entries, nil
+}
+
+// readLines reads a list file: one entry per line, with blank lines and
+// lines starting with # ignored
+func readLines(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return scanLines(f)
+}
+
+// scanLines reads entries in the readLines format from r
+func scanLines(r io.Reader) ([]string, error) {
+	var entries []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries, scanner.Err()
+}
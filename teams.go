@@ -0,0 +1,216 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// maxTeamAttempts bounds how many shuffles teams tries before deciding the
+// constraints cannot be met
+const maxTeamAttempts = 10000
+
+var teamsCmd = &cobra.Command{
+	Use:   "teams",
+	Short: "Split people into random teams, honoring avoid and pair constraints",
+	Long: `Split the people listed in a file (one per line) into random teams of
+--size. --avoid "alice:bob" keeps two people apart and --pair "cara:dan"
+keeps them together; both can be given more than once.
+
+The seed is always printed, and --seed repeats a draw exactly, so a result
+can be checked later.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		size, _ := cmd.Flags().GetInt("size")
+		from, _ := cmd.Flags().GetString("from")
+		avoids, _ := cmd.Flags().GetStringArray("avoid")
+		pairs, _ := cmd.Flags().GetStringArray("pair")
+
+		if from == "" {
+			log.Fatal("Specify a file of people with --from")
+		}
+		people, err := readLines(from)
+		if err != nil {
+			log.Fatal("Failed to read people:", err)
+		}
+		if size < 1 || size > len(people) {
+			log.Fatalf("Size must be between 1 and the number of people (%d)", len(people))
+		}
+
+		index := map[string]int{}
+		for i, person := range people {
+			if _, ok := index[person]; ok {
+				log.Fatalf("'%s' is listed twice", person)
+			}
+			index[person] = i
+		}
+		avoid, err := parsePairs(avoids, index)
+		if err != nil {
+			log.Fatal("Invalid --avoid: ", err)
+		}
+		pair, err := parsePairs(pairs, index)
+		if err != nil {
+			log.Fatal("Invalid --pair: ", err)
+		}
+
+		teams, err := makeTeams(len(people), size, avoid, pair)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if verbose {
+			printProvenance()
+		}
+
+		for i, team := range teams {
+			names := make([]string, len(team))
+			for j, p := range team {
+				names[j] = people[p]
+			}
+			switch {
+			case quiet:
+				fmt.Println(strings.Join(names, ", "))
+			case a11y:
+				fmt.Printf("Team %d: %s.\n", i+1, strings.Join(names, ", "))
+			default:
+				if i == 0 {
+					fmt.Printf("\n🎲 %d teams from %d people...\n", len(teams), len(people))
+				}
+				fmt.Printf("  Team %d: %s\n", i+1, strings.Join(names, ", "))
+			}
+		}
+
+		if !quiet {
+			fmt.Printf("\nSeed: %d (use --seed to repeat this draw)\n", rngSeed)
+		}
+	},
+}
+
+func init() {
+	teamsCmd.Flags().Int("size", 2, "People per team; the last team takes any remainder")
+	teamsCmd.Flags().String("from", "", "File listing one person per line")
+	teamsCmd.Flags().StringArray("avoid", nil, "Two people who must not share a team, as a:b")
+	teamsCmd.Flags().StringArray("pair", nil, "Two people who must share a team, as a:b")
+}
+
+// parsePairs turns "a:b" constraints into index pairs
+func parsePairs(specs []string, index map[string]int) ([][2]int, error) {
+	var pairs [][2]int
+	for _, spec := range specs {
+		a, b, ok := strings.Cut(spec, ":")
+		if !ok {
+			return nil, fmt.Errorf("%q should look like alice:bob", spec)
+		}
+		i, ok := index[strings.TrimSpace(a)]
+		if !ok {
+			return nil, fmt.Errorf("'%s' is not in the list", a)
+		}
+		j, ok := index[strings.TrimSpace(b)]
+		if !ok {
+			return nil, fmt.Errorf("'%s' is not in the list", b)
+		}
+		if i == j {
+			return nil, fmt.Errorf("%q names the same person twice", spec)
+		}
+		pairs = append(pairs, [2]int{i, j})
+	}
+	return pairs, nil
+}
+
+// makeTeams splits n people into teams of size (the last one smaller if it
+// does not divide evenly). People who must pair are merged into one unit,
+// units are shuffled, and each goes to a random team with room and no one it
+// must avoid; the whole draw is retried if a unit has nowhere to go.
+func makeTeams(n, size int, avoid, pair [][2]int) ([][]int, error) {
+	// Merge must-pair people into units
+	unit := make([]int, n)
+	for i := range unit {
+		unit[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if unit[i] != i {
+			unit[i] = find(unit[i])
+		}
+		return unit[i]
+	}
+	for _, p := range pair {
+		unit[find(p[0])] = find(p[1])
+	}
+	members := map[int][]int{}
+	for i := 0; i < n; i++ {
+		members[find(i)] = append(members[find(i)], i)
+	}
+	var units [][]int
+	for i := 0; i < n; i++ {
+		if group, ok := members[i]; ok {
+			if len(group) > size {
+				return nil, fmt.Errorf("%d people must pair together but teams hold %d", len(group), size)
+			}
+			units = append(units, group)
+		}
+	}
+
+	for _, a := range avoid {
+		if find(a[0]) == find(a[1]) {
+			return nil, fmt.Errorf("the same two people must both pair and avoid each other")
+		}
+	}
+	conflicts := map[[2]int]bool{}
+	for _, a := range avoid {
+		conflicts[[2]int{a[0], a[1]}] = true
+		conflicts[[2]int{a[1], a[0]}] = true
+	}
+
+	count := (n + size - 1) / size
+	capacity := make([]int, count)
+	for i := range capacity {
+		capacity[i] = size
+	}
+	capacity[count-1] = n - size*(count-1)
+
+	for attempt := 0; attempt < maxTeamAttempts; attempt++ {
+		rng.Shuffle(len(units), func(i, j int) { units[i], units[j] = units[j], units[i] })
+
+		// Place bigger units first so they are not squeezed out
+		order := append([][]int(nil), units...)
+		sort.SliceStable(order, func(i, j int) bool { return len(order[i]) > len(order[j]) })
+
+		teams := make([][]int, count)
+		placed := true
+		for _, u := range order {
+			var options []int
+			for t, team := range teams {
+				if len(team)+len(u) > capacity[t] || clashes(team, u, conflicts) {
+					continue
+				}
+				options = append(options, t)
+			}
+			if len(options) == 0 {
+				placed = false
+				break
+			}
+			t := options[rng.Intn(len(options))]
+			teams[t] = append(teams[t], u...)
+		}
+		if placed {
+			return teams, nil
+		}
+	}
+	return nil, fmt.Errorf("no split found that honors every constraint after %d tries", maxTeamAttempts)
+}
+
+// clashes reports whether anyone in a unit must avoid someone on the team
+func clashes(team, unit []int, conflicts map[[2]int]bool) bool {
+	for _, a := range team {
+		for _, b := range unit {
+			if conflicts[[2]int{a, b}] {
+				return true
+			}
+		}
+	}
+	return false
+}
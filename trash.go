@@ -0,0 +1,349 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// TrashItem is a deleted config, wordlist or macro, kept with its state and
+// history until it is restored or purged. Data is the file's contents, or a
+// macro's expression.
+type TrashItem struct {
+	Kind      string          `json:"kind"`
+	Name      string          `json:"name"`
+	Data      string          `json:"data"`
+	State     json.RawMessage `json:"state,omitempty"`
+	Versions  []ConfigVersion `json:"versions,omitempty"`
+	DeletedAt time.Time       `json:"deleted_at"`
+}
+
+var trashCmd = &cobra.Command{
+	Use:   "trash",
+	Short: "List deleted configurations, wordlists and macros that can be restored",
+	Long: `Deleting a configuration or macro, or removing a wordlist, moves it to
+the trash, along with a configuration's state and saved versions:
+
+  roll delete coffee
+  roll trash
+  roll trash restore coffee
+
+Items are purged once they have been in the trash for trash_days days (30
+unless set in settings.toml; 0 keeps them until trash empty).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		items, err := loadTrash()
+		if err != nil {
+			log.Fatal("Failed to load trash:", err)
+		}
+		if len(items) == 0 {
+			if !quiet {
+				fmt.Println("The trash is empty")
+			}
+			return
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if !quiet && !a11y {
+			fmt.Fprintln(w, "TYPE\tNAME\tDELETED\tPURGED")
+		}
+		for _, item := range items {
+			purged := "never"
+			if settings.TrashDays > 0 {
+				purged = formatTime(item.DeletedAt.AddDate(0, 0, settings.TrashDays))
+			}
+			switch {
+			case quiet:
+				fmt.Printf("%s\t%s\n", item.Kind, item.Name)
+			case a11y:
+				fmt.Printf("%s %s, deleted %s, purged %s.\n", strings.ToUpper(item.Kind[:1])+item.Kind[1:], item.Name, since(item.DeletedAt), purged)
+			default:
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", item.Kind, item.Name, since(item.DeletedAt), purged)
+			}
+		}
+		w.Flush()
+	},
+}
+
+var trashListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the trash, newest first",
+	Args:  cobra.NoArgs,
+	Run:   trashCmd.Run,
+}
+
+var trashRestoreCmd = &cobra.Command{
+	Use:   "restore [name]",
+	Short: "Restore the most recently deleted item with this name",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		kind, _ := cmd.Flags().GetString("type")
+		name := args[0]
+
+		items, err := loadTrash()
+		if err != nil {
+			log.Fatal("Failed to load trash:", err)
+		}
+		var item *TrashItem
+		for i := range items {
+			if items[i].Name == name && (kind == "" || items[i].Kind == kind) {
+				item = &items[i]
+				break
+			}
+		}
+		if item == nil {
+			log.Fatalf("Nothing named '%s' in the trash", name)
+		}
+
+		if item.Kind == "macro" {
+			if _, exists, _ := lookupMacro(name); exists {
+				log.Fatalf("A macro named '%s' already exists; delete it first", name)
+			}
+			err = db.Update(func(tx *bolt.Tx) error {
+				b, err := tx.CreateBucketIfNotExists([]byte("macros"))
+				if err != nil {
+					return err
+				}
+				if err := b.Put([]byte(name), []byte(item.Data)); err != nil {
+					return err
+				}
+				return deleteTrash(tx, *item)
+			})
+			if err != nil {
+				log.Fatal("Failed to restore macro:", err)
+			}
+			fmt.Printf("Restored macro '%s'\n", name)
+			return
+		}
+
+		path := trashPath(*item)
+		if _, err := os.Stat(path); err == nil {
+			log.Fatalf("A %s named '%s' already exists; delete or rename it first", item.Kind, name)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			log.Fatal("Failed to create directory:", err)
+		}
+
+		// Write the file first so the item only leaves the trash once it is
+		// back, and take the file away again if the rest cannot be restored
+		if err := os.WriteFile(path, []byte(item.Data), 0644); err != nil {
+			log.Fatal("Failed to restore file:", err)
+		}
+		err = db.Update(func(tx *bolt.Tx) error {
+			if item.State != nil {
+				b, err := tx.CreateBucketIfNotExists([]byte("states"))
+				if err != nil {
+					return err
+				}
+				if err := b.Put([]byte(name), item.State); err != nil {
+					return err
+				}
+			}
+			if err := restoreVersions(tx, name, item.Versions); err != nil {
+				return err
+			}
+			return deleteTrash(tx, *item)
+		})
+		if err != nil {
+			os.Remove(path)
+			log.Fatal("Failed to restore state:", err)
+		}
+
+		fmt.Printf("Restored %s '%s'\n", item.Kind, name)
+	},
+}
+
+var trashEmptyCmd = &cobra.Command{
+	Use:   "empty",
+	Short: "Permanently delete everything in the trash",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := db.Update(func(tx *bolt.Tx) error {
+			if tx.Bucket([]byte("trash")) == nil {
+				return nil
+			}
+			return tx.DeleteBucket([]byte("trash"))
+		})
+		if err != nil {
+			log.Fatal("Failed to empty trash:", err)
+		}
+		fmt.Println("Emptied the trash")
+	},
+}
+
+func init() {
+	trashCmd.AddCommand(trashListCmd)
+	trashCmd.AddCommand(trashRestoreCmd)
+	trashCmd.AddCommand(trashEmptyCmd)
+	trashRestoreCmd.Flags().String("type", "", "Only restore this type of item: config, wordlist or macro")
+	trashRestoreCmd.ValidArgsFunction = completeArgs(trashNames)
+}
+
+// trashPath is where a trashed config or wordlist's file goes back to
+func trashPath(item TrashItem) string {
+	if item.Kind == "wordlist" {
+		return wordlistPath(item.Name)
+	}
+	return filepath.Join(configDir, item.Name+".toml")
+}
+
+// moveToTrash stores an item in the trash as part of the transaction that
+// deletes it, purging anything past the retention period at the same time
+func moveToTrash(tx *bolt.Tx, item TrashItem) error {
+	b, err := tx.CreateBucketIfNotExists([]byte("trash"))
+	if err != nil {
+		return err
+	}
+	if err := purgeTrash(b); err != nil {
+		return err
+	}
+
+	seq, err := b.NextSequence()
+	if err != nil {
+		return err
+	}
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+
+	item.DeletedAt = time.Now().UTC()
+	value, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, value)
+}
+
+// purgeTrash deletes items older than the trash_days setting
+func purgeTrash(b *bolt.Bucket) error {
+	if settings.TrashDays <= 0 {
+		return nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -settings.TrashDays)
+
+	var expired [][]byte
+	err := b.ForEach(func(k, v []byte) error {
+		var item TrashItem
+		if err := json.Unmarshal(v, &item); err != nil {
+			return err
+		}
+		if item.DeletedAt.Before(cutoff) {
+			expired = append(expired, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, k := range expired {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadTrash purges expired items and returns the rest, newest first
+func loadTrash() ([]TrashItem, error) {
+	var items []TrashItem
+	err := db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte("trash"))
+		if b == nil {
+			return nil
+		}
+		if err := purgeTrash(b); err != nil {
+			return err
+		}
+		return b.ForEach(func(k, v []byte) error {
+			var item TrashItem
+			if err := json.Unmarshal(v, &item); err != nil {
+				return err
+			}
+			items = append([]TrashItem{item}, items...)
+			return nil
+		})
+	})
+	return items, err
+}
+
+// deleteTrash removes one item from the trash
+func deleteTrash(tx *bolt.Tx, item TrashItem) error {
+	b := tx.Bucket([]byte("trash"))
+	if b == nil {
+		return nil
+	}
+	var match []byte
+	err := b.ForEach(func(k, v []byte) error {
+		var stored TrashItem
+		if err := json.Unmarshal(v, &stored); err != nil {
+			return err
+		}
+		if stored.Kind == item.Kind && stored.Name == item.Name && stored.DeletedAt.Equal(item.DeletedAt) {
+			match = append([]byte(nil), k...)
+		}
+		return nil
+	})
+	if err != nil || match == nil {
+		return err
+	}
+	return b.Delete(match)
+}
+
+// restoreVersions puts a config's saved versions back, replacing any kept
+// under the same name since it was deleted
+func restoreVersions(tx *bolt.Tx, name string, versions []ConfigVersion) error {
+	if len(versions) == 0 {
+		return nil
+	}
+	root, err := tx.CreateBucketIfNotExists([]byte("versions"))
+	if err != nil {
+		return err
+	}
+	if root.Bucket([]byte(name)) != nil {
+		if err := root.DeleteBucket([]byte(name)); err != nil {
+			return err
+		}
+	}
+	b, err := root.CreateBucket([]byte(name))
+	if err != nil {
+		return err
+	}
+	for _, version := range versions {
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		key := make([]byte, 8)
+		binary.BigEndian.PutUint64(key, seq)
+
+		value, err := json.Marshal(version)
+		if err != nil {
+			return err
+		}
+		if err := b.Put(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// trashNames returns the names of everything in the trash
+func trashNames() []string {
+	items, err := loadTrash()
+	if err != nil {
+		return nil
+	}
+	names := make([]string, len(items))
+	for i, item := range items {
+		names[i] = item.Name
+	}
+	return names
+}
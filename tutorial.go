@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/spf13/cobra"
+)
+
+var tutorialCmd = &cobra.Command{
+	Use:   "tutorial",
+	Short: "Walk through creating and rolling a sample configuration",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		in := bufio.NewReader(os.Stdin)
+
+		// Everything stays in memory; nothing touches the config dir or database
+		config := &Config{Name: "tutorial", Chance: 10, Grace: 15, Pity: 5, Variance: 3}
+		state := &State{}
+		var history []RollResult
+
+		fmt.Println("Welcome to roll!")
+		fmt.Println("\nThis tutorial runs in a sandbox: nothing you do here is saved.")
+		fmt.Println("\nA roll configuration has four numbers:")
+		fmt.Println("  chance    the base chance of success, in percent")
+		fmt.Println("  grace     how many percent each failure adds to the next roll")
+		fmt.Println("  pity      how many failures can stack up before grace stops growing")
+		fmt.Println("  variance  a 1-in-N style chance that a roll gets one extra grace bonus")
+		fmt.Println("\nHere is a sample configuration, as it would be saved by")
+		fmt.Println("`roll create tutorial 10 15 5 3`:")
+		fmt.Println()
+		toml.NewEncoder(os.Stdout).Encode(config)
+		waitForEnter(in, "\nPress Enter to start rolling...")
+
+		fmt.Println("\nEach failure raises the pity counter, and each success resets it.")
+		for {
+			result := rollConfig(config, state)
+			history = append(history, result)
+			printRoll(config.Name, config, result)
+
+			if result.Success {
+				fmt.Println("\nThe pity counter is back to 0, so the next roll starts from the base chance again.")
+			} else {
+				fmt.Printf("\nThe pity counter is now %d, so the next roll gets %d%% extra.\n",
+					state.PityCounter, state.PityCounter*config.Grace)
+			}
+
+			if !waitForEnter(in, "\nPress Enter to roll again, or type q and Enter to move on: ") {
+				break
+			}
+		}
+
+		fmt.Println("\nInstead of rolling, `roll odds [name]` shows the exact odds of a saved")
+		fmt.Println("configuration. For this one they are:")
+		odds, err := computeOdds(config, 0)
+		if err != nil {
+			log.Fatal("Failed to compute odds:", err)
+		}
+		fmt.Printf("  Expected rolls until success: %s\n", formatFloat(odds.Expected, settings.Precision))
+		for _, p := range []float64{0.5, 0.9, 0.99} {
+			fmt.Printf("  %2.0f%% chance of success within %d rolls\n", p*100, odds.RollsFor(p))
+		}
+		waitForEnter(in, "\nPress Enter to review your rolls...")
+
+		fmt.Println("\nYour rolls this session:")
+		for i, result := range history {
+			outcome := "failed"
+			if result.Success {
+				outcome = "succeeded"
+			}
+			fmt.Printf("  %2d. pity %d, chance %3d%%, rolled %3d, %s\n",
+				i+1, result.Pity, result.EffectiveChance, result.Roll, outcome)
+		}
+
+		fmt.Println("\nThat's it! To make your own, try:")
+		fmt.Println("  roll create [name] [chance] [grace] [pity] [variance]")
+		fmt.Println("  roll roll [name]")
+		fmt.Println("  roll odds [name]")
+		fmt.Println("  roll list --table")
+		fmt.Println("  roll lint [name]")
+	},
+}
+
+// waitForEnter prints a prompt and waits for Enter. It returns false if the
+// user typed q or input ended.
+func waitForEnter(in *bufio.Reader, prompt string) bool {
+	fmt.Print(prompt)
+	line, err := in.ReadString('\n')
+	if err != nil {
+		fmt.Println()
+		return false
+	}
+	return !strings.EqualFold(strings.TrimSpace(line), "q")
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WildRoll is a Savage Worlds trait roll: the trait die and the wild die
+// both explode and the higher one counts, plus any modifiers
+type WildRoll struct {
+	Trait    Die
+	Wild     Die
+	Modifier int
+}
+
+// Total returns the higher die plus modifiers
+func (r WildRoll) Total() int {
+	return max(r.Trait.Value(), r.Wild.Value()) + r.Modifier
+}
+
+// CriticalFailure reports snake eyes: both dice showing 1
+func (r WildRoll) CriticalFailure() bool {
+	return r.Trait.Faces[0] == 1 && r.Wild.Faces[0] == 1
+}
+
+// wildTrait finds the trait die of an expression rolled with a wild die and
+// makes it explode. The expression must hold exactly one die, as in d8+1.
+func wildTrait(expr *DiceExpr) (int, error) {
+	trait := -1
+	for i, term := range expr.Terms {
+		if term.Sides == 0 {
+			continue
+		}
+		if trait >= 0 || term.Count != 1 || term.Sign < 0 {
+			return 0, fmt.Errorf("a wild die goes with a single trait die, as in d8+1")
+		}
+		if term.Fudge || term.Digits > 0 || term.Percentile || term.Select != "" || term.Sides < 2 {
+			return 0, fmt.Errorf("%s cannot be rolled with a wild die", term)
+		}
+		trait = i
+	}
+	if trait < 0 {
+		return 0, fmt.Errorf("a wild die goes with a single trait die, as in d8+1")
+	}
+	expr.Terms[trait].Explode = true
+	return trait, nil
+}
+
+// parseWildDie reads the wild die, e.g. d6, which always explodes
+func parseWildDie(src string) (DiceTerm, error) {
+	expr, err := parseDice(src)
+	if err != nil {
+		return DiceTerm{}, err
+	}
+	term := expr.Terms[0]
+	if len(expr.Terms) != 1 || term.Count != 1 || term.Sign < 0 || term.Fudge || term.Digits > 0 || term.Percentile || term.Sides < 2 {
+		return DiceTerm{}, fmt.Errorf("the wild die must be a single die such as d6")
+	}
+	term.Explode = true
+	term.Select = ""
+	return term, nil
+}
+
+// rollWild rolls the trait die and the wild die
+func rollWild(expr *DiceExpr, trait int, wild DiceTerm) WildRoll {
+	roll := WildRoll{
+		Trait: expr.rollDie(expr.Terms[trait]),
+		Wild:  expr.rollDie(wild),
+	}
+	for _, term := range expr.Terms {
+		if term.Sides == 0 {
+			roll.Modifier += term.Sign * term.Value
+		}
+	}
+	return roll
+}
+
+// wildDistribution works out the exact distribution of a wild die roll
+func wildDistribution(expr *DiceExpr, trait int, wild DiceTerm) Distribution {
+	dist := higherOf(expr.dieDistribution(expr.Terms[trait]), expr.dieDistribution(wild))
+	for _, term := range expr.Terms {
+		if term.Sides == 0 {
+			dist = convolve(dist, Distribution{term.Sign * term.Value: 1})
+		}
+	}
+	return dist
+}
+
+// higherOf returns the distribution of the higher of two independent
+// results: P(max <= v) = P(A <= v) * P(B <= v)
+func higherOf(a, b Distribution) Distribution {
+	seen := map[int]bool{}
+	var values []int
+	for _, d := range []Distribution{a, b} {
+		for v := range d {
+			if !seen[v] {
+				seen[v] = true
+				values = append(values, v)
+			}
+		}
+	}
+	sort.Ints(values)
+
+	dist := Distribution{}
+	belowA, belowB, before := 0.0, 0.0, 0.0
+	for _, v := range values {
+		belowA += a[v]
+		belowB += b[v]
+		if p := belowA*belowB - before; p > 0 {
+			dist[v] = p
+		}
+		before = belowA * belowB
+	}
+	return dist
+}
+
+// chain shows a die's rolls, e.g. "6! 6! 2 = 14"
+func chain(die Die) string {
+	faces := make([]string, len(die.Faces))
+	for i, face := range die.Faces {
+		faces[i] = strconv.Itoa(face)
+		if i < len(die.Faces)-1 {
+			faces[i] += "!"
+		}
+	}
+	if len(faces) == 1 {
+		return faces[0]
+	}
+	return strings.Join(faces, " ") + " = " + strconv.Itoa(die.Value())
+}
+
+// printWildRoll prints both chains of a wild die roll and the one kept
+func printWildRoll(name string, expr *DiceExpr, trait int, wild DiceTerm, roll WildRoll, shift int) {
+	total := roll.Total() + shift
+	kept := "trait"
+	if roll.Wild.Value() > roll.Trait.Value() {
+		kept = "wild"
+	}
+
+	if verbose {
+		printProvenance()
+		for _, d := range []struct {
+			die   Die
+			sides int
+		}{{roll.Trait, expr.Terms[trait].Sides}, {roll.Wild, wild.Sides}} {
+			for _, face := range d.die.Faces {
+				fmt.Printf("Raw draw: %d of [0, %d), roll = %d\n", face-1, d.sides, face)
+			}
+		}
+	}
+
+	if quiet {
+		fmt.Println(total)
+		return
+	}
+
+	if a11y {
+		fmt.Printf("Rolling %s with a d%d wild die. Result: %d.\n", name, wild.Sides, total)
+		fmt.Printf("The trait die rolled %s and the wild die rolled %s; the %s die counts.\n",
+			listAnd(roll.Trait.Faces), listAnd(roll.Wild.Faces), kept)
+		if roll.CriticalFailure() {
+			fmt.Println("Both dice showed 1: critical failure.")
+		}
+		return
+	}
+
+	fmt.Printf("\n🎲 Rolling %s with a d%d wild die...\n", name, wild.Sides)
+	fmt.Printf("Trait die: %s\n", chain(roll.Trait))
+	fmt.Printf("Wild die: %s\n", chain(roll.Wild))
+	fmt.Printf("Keeping the %s die\n", kept)
+	fmt.Printf("Total: %d\n", total)
+	if roll.CriticalFailure() {
+		fmt.Println("❌ Critical failure! Both dice showed 1")
+	}
+}
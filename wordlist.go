@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"unicode"
+
+	bolt "go.etcd.io/bbolt"
+	"github.com/spf13/cobra"
+)
+
+// bundledWordlist is the name of the wordlist shipped with roll
+const bundledWordlist = "bundled"
+
+var wordlistCmd = &cobra.Command{
+	Use:   "wordlist",
+	Short: "List installed wordlists for password",
+	Long: `Wordlists are the words password picks from. Install your own with
+wordlist add; plain lists (one word per line) and diceware lists, whose
+lines start with the dice that pick the word, both work:
+
+  roll wordlist add eff-large.txt
+  roll password --wordlist eff-large
+
+Set wordlist = "eff-large" in settings.toml (or ROLL_WORDLIST) to make it
+the default.
+
+Wordlists only feed password. Name generators such as npc draw from tables
+instead (see spark tables).`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		names := wordlistNames()
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		if !quiet && !a11y {
+			fmt.Fprintln(w, "NAME\tWORDS\tBITS PER WORD")
+		}
+		for _, name := range names {
+			words, err := loadWordlist(name)
+			if err != nil {
+				log.Fatalf("Failed to load wordlist '%s': %v", name, err)
+			}
+			marker := ""
+			if name == defaultWordlist() {
+				marker = " (default)"
+			}
+			bits := formatFloat(math.Log2(float64(len(words))), 1)
+			switch {
+			case quiet:
+				fmt.Println(name)
+			case a11y:
+				fmt.Printf("%s%s: %s words, %s bits per word.\n", name, marker, formatCount(len(words)), bits)
+			default:
+				fmt.Fprintf(w, "%s%s\t%s\t%s\n", name, marker, formatCount(len(words)), bits)
+			}
+		}
+		w.Flush()
+	},
+}
+
+var wordlistAddCmd = &cobra.Command{
+	Use:   "add [file]",
+	Short: "Install a wordlist from a file",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		name, _ := cmd.Flags().GetString("name")
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(args[0]), filepath.Ext(args[0]))
+		}
+		if name == bundledWordlist || strings.ContainsAny(name, `/\`) {
+			log.Fatalf("'%s' cannot be used as a wordlist name", name)
+		}
+
+		lines, err := readLines(args[0])
+		if err != nil {
+			log.Fatal("Failed to read wordlist:", err)
+		}
+		words, err := parseWordlist(lines)
+		if err != nil {
+			log.Fatal("Invalid wordlist: ", err)
+		}
+
+		path := wordlistPath(name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			log.Fatal("Failed to create wordlist directory:", err)
+		}
+		if err := os.WriteFile(path, []byte(strings.Join(words, "\n")+"\n"), 0644); err != nil {
+			log.Fatal("Failed to save wordlist:", err)
+		}
+
+		fmt.Printf("Installed wordlist '%s': %s words, %s bits per word\n",
+			name, formatCount(len(words)), formatFloat(math.Log2(float64(len(words))), 1))
+	},
+}
+
+var wordlistRemoveCmd = &cobra.Command{
+	Use:   "remove [name]",
+	Short: "Remove an installed wordlist",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if args[0] == bundledWordlist {
+			log.Fatal("The bundled wordlist cannot be removed")
+		}
+		data, err := os.ReadFile(wordlistPath(args[0]))
+		if err != nil {
+			log.Fatal("Failed to read wordlist:", err)
+		}
+		err = db.Update(func(tx *bolt.Tx) error {
+			return moveToTrash(tx, TrashItem{Kind: "wordlist", Name: args[0], Data: string(data)})
+		})
+		if err != nil {
+			log.Fatal("Failed to move wordlist to the trash:", err)
+		}
+		if err := os.Remove(wordlistPath(args[0])); err != nil {
+			log.Fatal("Failed to remove wordlist:", err)
+		}
+		fmt.Printf("Removed wordlist '%s' (restore it with: roll trash restore %s)\n", args[0], shellArg(args[0]))
+	},
+}
+
+func init() {
+	wordlistCmd.AddCommand(wordlistAddCmd)
+	wordlistCmd.AddCommand(wordlistRemoveCmd)
+	wordlistAddCmd.Flags().String("name", "", "Name to install the list under (default the file name)")
+	wordlistRemoveCmd.ValidArgsFunction = completeArgs(func() []string { return wordlistNames()[1:] })
+}
+
+func wordlistPath(name string) string {
+	return filepath.Join(configDir, "wordlists", name+".txt")
+}
+
+// defaultWordlist is the wordlist from settings, or the bundled one
+func defaultWordlist() string {
+	if settings.Wordlist != "" {
+		return settings.Wordlist
+	}
+	return bundledWordlist
+}
+
+// wordlistNames returns the bundled wordlist and every installed one
+func wordlistNames() []string {
+	names := []string{bundledWordlist}
+	if files, err := os.ReadDir(filepath.Join(configDir, "wordlists")); err == nil {
+		for _, file := range files {
+			if filepath.Ext(file.Name()) == ".txt" {
+				names = append(names, strings.TrimSuffix(file.Name(), ".txt"))
+			}
+		}
+	}
+	sort.Strings(names[1:])
+	return names
+}
+
+// loadWordlist returns the words of an installed or the bundled wordlist
+func loadWordlist(name string) ([]string, error) {
+	if name == bundledWordlist {
+		return bundledWords, nil
+	}
+	lines, err := readLines(wordlistPath(name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("no wordlist named '%s'", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseWordlist(lines)
+}
+
+// parseWordlist reads words from plain or diceware lines, dropping the dice
+// index in front of a diceware word and any repeated words
+func parseWordlist(lines []string) ([]string, error) {
+	seen := map[string]bool{}
+	var words []string
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		word := line
+		if len(fields) == 2 && strings.IndexFunc(fields[0], func(r rune) bool { return !unicode.IsDigit(r) }) < 0 {
+			word = fields[1]
+		}
+		if !seen[word] {
+			seen[word] = true
+			words = append(words, word)
+		}
+	}
+	if len(words) < 2 {
+		return nil, fmt.Errorf("a wordlist needs at least two different words")
+	}
+	return words, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import "strings"
+
+// bundledWords is the wordlist password uses when no other list is chosen:
+// 1542 short, common English words, so each word adds about 10.6 bits.
+var bundledWords = strings.Fields(`
+	able acid acorn acre actor adapt adobe adult aged agent agile aging
+	agree ahead aide aim air aisle alarm album alert algae alibi alien
+	alike alive alley allow alloy almond aloe alone alpha also alter amber
+	amend amino among ample amuse angel angle ankle annex anvil apart apple
+	apron aqua arbor arch arena argue arise armor army aroma arrow art
+	ash aside asset atlas atom attic audio audit aunt avid avoid awake
+	award aware axis bacon badge bagel baker balmy bamboo banjo barn baron
+	basil basin batch bath baton bay beach beam bean bear beard beast
+	beech beef beet begin being belly bench berry bike bird birth bison
+	black blade blank blast blaze bleak blend bless blimp blink bliss block
+	bloom blue blunt blush board boast boat body bold bolt bonus book
+	boost boot booth bore boss botany bottle bounce bowl box brain brake
+	brand brass brave bread break brick bride brief brim brisk broad broil
+	brook broom brown brush bubble bucket buddy budget buggy build bulb bulk
+	bunch bunny burst bush busy butter button buyer buzz cabin cable cactus
+	cadet cake calm camel cameo camp canal candy canoe canopy canvas canyon
+	cape cargo carol carpet carrot carry cart carve case cash castle cause
+	cave cedar cell cello chain chair chalk champ chant chaos charm chart
+	chase cheap check cheek cheer chess chest chew chief child chili chime
+	chip chirp choir chop chord chose chunk cider cinema circle citrus city
+	civic civil claim clamp clap clash clasp class claw clay clean clear
+	clerk click cliff climb cling clip cloak clock close cloth cloud clover
+	clown club clue coach coal coast coat cobra cocoa coconut code coin
+	cola cold comet comic comma cone coral cord core cork corn cost
+	cotton couch cough count court cove cover cozy crab craft crane crash
+	crate crawl crayon crazy cream creek crest crew crisp crop cross crow
+	crowd crown crumb crush crust cube cupid curb cure curl curry curve
+	cycle daily dairy daisy dance dandy dare dash data dawn deal debut
+	decal decoy deed deep deer delta denim dense depth derby desert desk
+	detail dial diary dice diet digit dime diner dingo dinner disco dish
+	ditch diver dizzy dock dodge dogma doll dolphin dome donor donut door
+	dose dove down dozen draft drag drain drama drape draw dream dress
+	drift drill drink drip drive drum dry duck dune dusk dust duty
+	dwarf eager eagle early earn earth easel east easy echo edge edit
+	eel egg eight elbow elder elect elegy elf elite elm else ember
+	emblem emery empty enamel end energy enjoy entry envoy epic equal era
+	error essay ether even event every exact exam exile exit expo extra
+	fable fabric face fact fade fair fairy faith fall false fame fancy
+	farm fast fault fauna favor feast feather fence fern ferry fetch fever
+	fiber field fifth fifty fig film final finch find fine fire firm
+	first fish five flag flair flame flap flash flask flat flax fleet
+	flesh flick flint float flock flood floor flora flour flow fluid flute
+	foam focus fog foil folk fond font food foot force forest forge
+	fork form fort forty forum fossil found fox frame fresh friar fridge
+	frog frost fruit fudge fuel full fun fungi funny fuse fuzzy gable
+	gadget gala galaxy gale gallon game gamma gap garage garden garlic gas
+	gate gauge gavel gaze gear gecko gem genie genre gentle ghost giant
+	gift giggle ginger giraffe given glad glade glass gleam glide glint globe
+	glory glove glow glue gnome goal goat gold golf good goose gorge
+	gourd grace grade grain grand grant grape graph grasp grass gravel gravy
+	great green greet grid grill grin grip groom group grove grow guard
+	guava guess guest guide guild guitar gulf gull gust gym habit hair
+	half hall halo hammer hand happy harbor hard harp hatch haven hawk
+	hazel head heap heart heat hedge heel helix helmet help hen herb
+	herd hero heron hiking hill hinge hippo hobby hockey holly home honey
+	hood hook hope horn horse hose host hotel hound hour house hover
+	hub hug human humid humor hurry husky hut hydra hymn icon idea
+	idle igloo image inch index ink inlet inn input iris iron island
+	ivory ivy jacket jade jaguar jam jar jazz jeans jelly jet jewel
+	jog join joke jolly journal joy judge juice jumbo jump jungle junior
+	jury just kale karma kayak keel keen kettle key kick kid kilt
+	kind king kiosk kite kitten kiwi knee knife knit knob knot koala
+	label lace ladder lady lagoon lake lamb lamp lance land lane lapel
+	large laser lasso latch late lava lawn layer lazy leaf leap learn
+	lease leash least leaves ledge lemon lens level lever libra lid life
+	lift light lilac lily limb lime limit linen lion lip list liter
+	live lizard llama load loaf loan lobby local lock lodge loft logic
+	lotus loud lounge love loyal lucky lull lumber lunar lunch lung lure
+	lyric macaw magic magnet maid major maker mango manor maple marble march
+	mare marsh mask mason mat match mayor maze meadow meal medal melon
+	memo mentor menu merit merry mesa metal meter mild mile milk mill
+	mimic mind mine mint minus mirth mist mitten mix moat model modem
+	mole moment monk month moon moose moral moss motel moth motor mound
+	mount mouse mouth movie mud muffin mule mural muse music mustard myth
+	nail name nap navy near neat nectar needle neon nerve nest net
+	never new next nice night nimble nine noble node noise noon north
+	nose note novel nugget number nurse nut nylon oak oar oasis oat
+	ocean octave odd offer often olive omega onion open opera optic orange
+	orbit orchid order organ otter ounce outer oval oven owl owner oxide
+	oyster pace pack paddle page paint palace palm panda panel panic pansy
+	pants paper parade parcel park parrot party pasta paste patch path patio
+	pause paw peace peach peak pear pearl pecan pedal penny pepper perch
+	permit pet petal phone photo piano pickle picnic piece pig pilot pine
+	pink pint pipe pirate pitch pivot pixel pizza place plain plan plane
+	plank plant plate plaza plot plow plum plump plush pocket poem poet
+	point polar pole polka pond pony pool poppy porch port pose posse
+	pot pouch powder power prank press price pride prime print prism prize
+	probe prose proud prune pulse puma pump punch pupil puppy purse puzzle
+	quail quart queen query quest quick quiet quill quilt quirk quiz quota
+	rabbit raccoon race radar radio raft rail rain raisin rake rally ramp
+	ranch range rapid raven razor reach ready realm rebel recipe reef reel
+	relax relay relic remedy remote rent reply rest retro rhino rhyme ribbon
+	rice rich ride ridge right rigid ring rinse ripe rise risk ritual
+	rival river road roast robe robin robot rock rocket rodeo roof room
+	root rope rose rotor rough round route rover royal ruby rudder rug
+	rugby ruler rumor rune rural rust saddle safari safe saga sage sail
+	saint salad salmon salon salsa salt sample sand satin sauce sauna save
+	scale scarf scene scent school scoop scope score scout scrap screen script
+	scroll scuba sea seal season seat second seed seesaw sense serum seven
+	shade shadow shaft shake shape share shark sharp shave shawl sheep shelf
+	shell shield shift shine ship shirt shock shoe shore short shovel show
+	shrub shrug sieve sift sign silk silver simple siren sister sixth size
+	skate sketch ski skill skirt skunk sky slab slate sled sleep sleet
+	slice slide slope sloth slow small smart smile smoke snack snail snake
+	sneeze snow soap soccer sock soda sofa soft solar solid solo sonic
+	soup south space spade spark spear spice spider spike spin spine spiral
+	spoon sport spray spring sprout spruce squad squid stable staff stage stair
+	stamp stand star start state steam steel stem step stew stick still
+	sting stock stone stool storm story stove straw stream street stripe strong
+	studio style sugar suit summer summit sun sunny super surf swamp swan
+	sweet swift swim swing sword syrup table tablet taco tail talent tally
+	tamale tango tank tape target task taste taxi tea teach team teapot
+	tempo tender tennis tent term test thank theme thick thorn thread three
+	thumb thyme ticket tide tiger tile timber time tinsel tiny toast today
+	toffee token tomato tonic tool tooth topaz torch total totem towel tower
+	town toy trace track trade trail train tray treat tree trend trial
+	tribe trick trim trio troll truck true trunk trust truth tuba tulip
+	tuna tundra tunnel turkey turnip turtle tutor tweed twig twin twist type
+	ultra umbra umpire uncle under union unit upper urban usher utmost vacuum
+	valid valley value valve vapor vault velvet vendor venue verb verse vest
+	veto vial video view vigor villa vine vinyl viola violet viper visa
+	visit visor vista vital vivid vocal voice volt vote voyage wafer wagon
+	waist walk wall walnut walrus wand warm wash wasp watch water wave
+	wax way wealth weave web wedge weed week well west whale wheat
+	wheel whip whisk white whole wick wide width wild will willow wind
+	window wing wink winter wire wise witty wizard wolf woman wonder wood
+	wool word work world worm wrap wreath wrist write yacht yard yarn
+	year yeast yellow yeti yield yoga yogurt young youth yoyo zebra zero
+	zest zinc zipper zodiac zone zoom
+`)